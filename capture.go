@@ -0,0 +1,102 @@
+package problemdetail
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+)
+
+// Capturer is an http.ResponseWriter that buffers the status code, the header and the body written by a handler,
+// instead of sending them to the client. So a middleware can replace a plain error response of a legacy handler with
+// a problem detail:
+//
+//	func middleware(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			c := problemdetail.NewCapturer(w)
+//			next.ServeHTTP(c, r)
+//			if c.NeedsProblem() {
+//				pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+//				_ = c.WriteProblem(pd)
+//				return
+//			}
+//			_ = c.Commit()
+//		})
+//	}
+//
+// Either Capturer.WriteProblem or Capturer.Commit must be called, otherwise nothing is sent to the client.
+type Capturer struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+// NewCapturer returns a Capturer that eventually writes to w.
+func NewCapturer(w http.ResponseWriter) *Capturer {
+	return &Capturer{w: w, header: make(http.Header)}
+}
+
+// Header implements http.ResponseWriter, it returns the captured header.
+func (c *Capturer) Header() http.Header { return c.header }
+
+// WriteHeader implements http.ResponseWriter, it captures the status code.
+func (c *Capturer) WriteHeader(code int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	c.status = code
+}
+
+// Write implements http.ResponseWriter, it captures the body.
+func (c *Capturer) Write(b []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	return c.body.Write(b)
+}
+
+// Status returns the captured status code, 200 if the handler wrote a body without a status code, and 0 if the
+// handler wrote nothing.
+func (c *Capturer) Status() int { return c.status }
+
+// IsProblem reports whether the captured response is already a problem detail, according to its content type.
+func (c *Capturer) IsProblem() bool {
+	mediaType, _, _ := mime.ParseMediaType(c.header.Get("Content-Type"))
+	return mediaType == "application/problem+json" || mediaType == "application/problem+xml"
+}
+
+// NeedsProblem reports whether the captured response is an error, a status code of 400 and above, that is not a
+// problem detail yet.
+func (c *Capturer) NeedsProblem() bool { return c.status >= 400 && !c.IsProblem() }
+
+// WriteProblem discards the captured body and writes the problem detail as JSON with the captured status code, or 500
+// if the handler wrote nothing. The captured header is kept, except its Content-Type and Content-Length.
+func (c *Capturer) WriteProblem(pd ProblemDetailer) error {
+	c.copyHeader("Content-Type", "Content-Length")
+	status := c.status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	return WriteJSON(c.w, pd, status)
+}
+
+// Commit writes the captured response as it is.
+func (c *Capturer) Commit() error {
+	c.copyHeader()
+	if c.wroteHeader {
+		c.w.WriteHeader(c.status)
+	}
+	_, err := c.w.Write(c.body.Bytes())
+	return err
+}
+
+// copyHeader copies the captured header to the underlying response writer, except the given names.
+func (c *Capturer) copyHeader(except ...string) {
+	header := c.header.Clone()
+	for _, name := range except {
+		header.Del(name)
+	}
+	for name, values := range header {
+		c.w.Header()[name] = values
+	}
+}
@@ -0,0 +1,74 @@
+package problemdetail_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+// problemMiddleware converts the plain error responses of the next handler into problem details.
+func problemMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := problemdetail.NewCapturer(w)
+		next.ServeHTTP(c, r)
+		if c.NeedsProblem() {
+			pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+			_ = c.WriteProblem(pd)
+			return
+		}
+		_ = c.Commit()
+	})
+}
+
+func TestCapturer_WriteProblem(t *testing.T) {
+	legacy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc")
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	rec := httptest.NewRecorder()
+	problemMiddleware(legacy).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	expectTrue(t, rec.Code == 404)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+	expectTrue(t, rec.Header().Get("X-Request-Id") == "abc")
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Not Found","status":404}`)
+}
+
+func TestCapturer_Commit(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    int
+		body    string
+	}{
+		{
+			name: "success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("ok"))
+			},
+			code: 200,
+			body: "ok",
+		},
+		{
+			name: "problem",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+				_ = problemdetail.WriteJSON(w, pd, http.StatusConflict)
+			},
+			code: 409,
+			body: `{"type":"about:blank","title":"Conflict","status":409}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		problemMiddleware(tt.handler).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		expectTrue(t, rec.Code == tt.code)
+		expectTrue(t, rec.Body.String() == tt.body)
+	}
+}
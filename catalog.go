@@ -0,0 +1,52 @@
+package problemdetail
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// catalogEntry is an entry of the catalog served by CatalogHandler.
+type catalogEntry struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+}
+
+// CatalogHandler returns a read-only handler that serves the problem types registered by RegisterType as a JSON array
+// of {"type", "title", "status"} objects, ordered by type. So API consumers can discover the problems an API may
+// return. The catalog is read on every request, so types registered later are included too.
+//
+// Only GET and HEAD are allowed, any other method is answered with a 405 (Method Not Allowed) problem.
+func CatalogHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			pd := New(Untyped, WithValidateLevel(LStandard), WithAllowedMethods(http.MethodGet, http.MethodHead))
+			_ = WriteJSON(w, pd, http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := json.Marshal(catalog())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	})
+}
+
+// catalog returns the registered problem types ordered by type.
+func catalog() []catalogEntry {
+	types.RLock()
+	defer types.RUnlock()
+
+	entries := make([]catalogEntry, 0, len(types.infos))
+	for _, uri := range sortedKeys(types.infos) {
+		info := types.infos[uri]
+		entries = append(entries, catalogEntry{Type: uri, Title: info.title, Status: info.status})
+	}
+	return entries
+}
@@ -0,0 +1,48 @@
+package problemdetail_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestCatalogHandler(t *testing.T) {
+	problemdetail.RegisterType("https://example.com/probs/catalog-b", "Catalog B.", 409)
+	problemdetail.RegisterType("https://example.com/probs/catalog-a", "Catalog A.", 404)
+
+	rec := httptest.NewRecorder()
+	problemdetail.CatalogHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/problems", nil))
+	expectTrue(t, rec.Code == 200)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/json; charset=utf-8")
+
+	var entries []struct {
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		Status int    `json:"status"`
+	}
+	expectTrue(t, json.Unmarshal(rec.Body.Bytes(), &entries) == nil)
+
+	a, b := -1, -1
+	for i, e := range entries {
+		switch e.Type {
+		case "https://example.com/probs/catalog-a":
+			a = i
+			expectTrue(t, e.Title == "Catalog A." && e.Status == 404)
+		case "https://example.com/probs/catalog-b":
+			b = i
+			expectTrue(t, e.Title == "Catalog B." && e.Status == 409)
+		}
+	}
+	expectTrue(t, a >= 0 && b > a)
+}
+
+func TestCatalogHandler_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	problemdetail.CatalogHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/problems", nil))
+	expectTrue(t, rec.Code == 405)
+	expectTrue(t, rec.Header().Get("Allow") == "GET, HEAD")
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+}
@@ -0,0 +1,30 @@
+package problemdetail
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+// codePattern is the pattern set by SetCodePattern, nil means any non-empty code is accepted.
+var codePattern atomic.Pointer[regexp.Regexp]
+
+// SetCodePattern sets the pattern that the codes given to WithCode must match, for example
+// regexp.MustCompile(`^ACME-\d{4}$`). A nil pattern, the default, accepts any non-empty code. It is safe for concurrent
+// use, but it is meant to be set once at program start.
+func SetCodePattern(pattern *regexp.Regexp) { codePattern.Store(pattern) }
+
+// WithCode adds the code extension member, a short and stable identifier of the problem, such as ACME-1001, that
+// clients and the documentation refer to for support. It is distinct from ProblemDetail.Type. An empty code, or a code
+// that does not match the pattern set by SetCodePattern, is reported as ErrCodeFormat when the problem detail is
+// validated.
+func WithCode(code string) Option {
+	return func(pd *ProblemDetail) {
+		pattern := codePattern.Load()
+		if code == "" || (pattern != nil && !pattern.MatchString(code)) {
+			pd.errs = append(pd.errs, fmt.Errorf("%w: %q", ErrCodeFormat, code))
+			return
+		}
+		WithExtension("code", code)(pd)
+	}
+}
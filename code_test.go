@@ -0,0 +1,51 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSON_WithCode(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCode("ACME-1001"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Forbidden","status":403,"code":"ACME-1001"}`)
+
+	data = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCode(""),
+	)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrCodeFormat))
+}
+
+func TestSetCodePattern(t *testing.T) {
+	problemdetail.SetCodePattern(regexp.MustCompile(`^ACME-\d{4}$`))
+	t.Cleanup(func() { problemdetail.SetCodePattern(nil) })
+
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCode("ACME-1001"),
+	)
+	err := problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, err == nil)
+
+	data = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCode("acme-1"),
+	)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrCodeFormat))
+	_, ok := data.Extensions()["code"]
+	expectTrue(t, !ok)
+}
@@ -0,0 +1,132 @@
+package problemdetail
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+)
+
+// defaultOptions are the options set by SetDefaultOptions.
+var defaultOptions atomic.Pointer[[]Option]
+
+// SetDefaultOptions sets the options that New applies to every problem detail, for organization-wide members such
+// as a service extension. The order is predictable: the default options are applied first, in the given order, then
+// the options given to New, so an option given to New overrides a default one, for example WithExtension("service",
+// "billing") replaces the default service member. The default options are not counted by WithStrictOptions. Calling
+// it without options removes the default options.
+//
+// It is safe for concurrent use, but it is meant to be set once at program start. The options are shared by all the
+// problem details, so they must not retain mutable state.
+func SetDefaultOptions(opts ...Option) {
+	if len(opts) == 0 {
+		defaultOptions.Store(nil)
+		return
+	}
+	opts = append([]Option(nil), opts...)
+	defaultOptions.Store(&opts)
+}
+
+// applyDefaultOptions applies the options set by SetDefaultOptions.
+func (p *ProblemDetail) applyDefaultOptions() {
+	opts := defaultOptions.Load()
+	if opts == nil {
+		return
+	}
+	for _, opt := range *opts {
+		opt(p)
+	}
+	p.applied = p.applied[:0]
+}
+
+// Defaults are the per-request defaults of the problem details, for example of a tenant in a multi-tenant service.
+// They are stored in a context by a middleware with ContextWithDefaults, and applied by WithContextDefaults.
+type Defaults struct {
+	// TypeBaseURL is the absolute URL that a relative ProblemDetail.Type is resolved against, it takes precedence over
+	// the one set by SetTypeBaseURL. Empty means the one set by SetTypeBaseURL.
+	TypeBaseURL string
+
+	// Service is the name of the service, it is added as the service extension member. Empty means no member.
+	Service string
+}
+
+// defaultsKey is the context key of the defaults stored by ContextWithDefaults.
+type defaultsKey struct{}
+
+// ContextWithDefaults returns a copy of ctx that carries the defaults, for WithContextDefaults.
+func ContextWithDefaults(ctx context.Context, d Defaults) context.Context {
+	return context.WithValue(ctx, defaultsKey{}, d)
+}
+
+// WithContextDefaults applies the defaults carried by ctx, as stored by ContextWithDefaults, so the handlers deep in
+// a request pick up the defaults injected by a middleware. A TypeBaseURL that is not an absolute URL is reported as
+// ErrTypeFormat when the problem detail is validated. If ctx carries no defaults, the option is a no-op.
+func WithContextDefaults(ctx context.Context) Option {
+	return func(pd *ProblemDetail) {
+		d, ok := ctx.Value(defaultsKey{}).(Defaults)
+		if !ok {
+			return
+		}
+		if d.TypeBaseURL != "" {
+			u, err := url.Parse(d.TypeBaseURL)
+			if err != nil || !u.IsAbs() {
+				err := fmt.Errorf("%w: type base URL %q is not absolute", ErrTypeFormat, d.TypeBaseURL)
+				pd.errs = append(pd.errs, err)
+			} else {
+				pd.typeBase = u
+			}
+		}
+		if d.Service != "" {
+			WithExtension("service", d.Service)(pd)
+		}
+	}
+}
+
+// WithDefaults makes the writers fill the empty members right before validation, for handlers that do not care to set
+// every member: ProblemDetail.Type defaults to Untyped, ProblemDetail.Title to the title of the status code and
+// ProblemDetail.Instance to a random "urn:uuid:" URN. The validation level is unchanged, so the members that have no
+// default, such as ProblemDetail.Detail, are still validated. Defaults are never applied by New itself.
+func WithDefaults() Option {
+	return func(pd *ProblemDetail) { pd.defaults = true }
+}
+
+// WithDefaults is the method form of the WithDefaults option, it returns the problem detail itself.
+func (p *ProblemDetail) WithDefaults() *ProblemDetail {
+	WithDefaults()(p)
+	return p
+}
+
+// fillDefaults fills the empty members if WithDefaults is set.
+func (p *ProblemDetail) fillDefaults() error {
+	if !p.defaults {
+		return nil
+	}
+	if p.Type == "" {
+		p.Type = Untyped
+	}
+	if p.Title == "" {
+		p.Title = statusTitle(p.Status)
+	}
+	if p.Instance == "" {
+		id, err := newUUID()
+		if err != nil {
+			return fmt.Errorf("default instance: %w", err)
+		}
+		p.Instance = "urn:uuid:" + id
+	}
+	return nil
+}
+
+// newUUID returns a random version 4 UUID.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9562#section-5.4
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4.
+	b[8] = b[8]&0x3f | 0x80 // variant 10.
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
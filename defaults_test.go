@@ -0,0 +1,119 @@
+package problemdetail_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSON_WithDefaults(t *testing.T) {
+	data := problemdetail.New("",
+		problemdetail.WithDefaults(),
+		problemdetail.WithDetail("The database is unreachable."),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 503)
+	expectTrue(t, err == nil)
+
+	expectTrue(t, data.Type == problemdetail.Untyped)
+	expectTrue(t, data.Title == "Service Unavailable")
+	uuid := regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	expectTrue(t, uuid.MatchString(data.Instance))
+}
+
+func TestWriteJSON_WithDefaultsMethod(t *testing.T) {
+	data := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithInstance("/account/12345/abc"),
+	).WithDefaults()
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrDetailRequired))
+	expectTrue(t, data.Type == "https://example.com/probs/out-of-credit")
+	expectTrue(t, data.Title == "You do not have enough credit.")
+	expectTrue(t, data.Instance == "/account/12345/abc")
+}
+
+func TestWriteJSON_WithoutDefaults(t *testing.T) {
+	data := problemdetail.New("")
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 503)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, data.Instance == "")
+}
+
+func TestSetDefaultOptions(t *testing.T) {
+	problemdetail.SetDefaultOptions(
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("service", "billing"),
+		problemdetail.WithTitle("Something went wrong."),
+	)
+	t.Cleanup(func() { problemdetail.SetDefaultOptions() })
+
+	data := problemdetail.New("https://example.com/probs/out-of-credit")
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expRaw := `{"type":"https://example.com/probs/out-of-credit","title":"Something went wrong.","status":403,"service":"billing"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	data = problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithStrictOptions(),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("service", "payments"),
+	)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expRaw = `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"service":"payments"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	problemdetail.SetDefaultOptions()
+	data = problemdetail.New("https://example.com/probs/out-of-credit")
+	expectTrue(t, data.Title == "")
+	expectTrue(t, len(data.Extensions()) == 0)
+}
+
+func TestWithContextDefaults(t *testing.T) {
+	problemdetail.SetTypeBaseURL("https://example.com/probs/")
+	t.Cleanup(func() { problemdetail.SetTypeBaseURL("") })
+
+	ctx := problemdetail.ContextWithDefaults(context.Background(), problemdetail.Defaults{
+		TypeBaseURL: "https://tenant.example.com/probs/",
+		Service:     "billing",
+	})
+	data := problemdetail.New("tenant-out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithContextDefaults(ctx),
+		problemdetail.WithTitle("You do not have enough credit."),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expRaw := `{"type":"https://tenant.example.com/probs/tenant-out-of-credit","title":"You do not have enough credit.","status":403,"service":"billing"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	data = problemdetail.New("tenant-out-of-credit", problemdetail.WithContextDefaults(context.Background()))
+	expectTrue(t, len(data.Extensions()) == 0)
+}
+
+func TestWithContextDefaults_InvalidTypeBaseURL(t *testing.T) {
+	ctx := problemdetail.ContextWithDefaults(context.Background(), problemdetail.Defaults{TypeBaseURL: "/probs/"})
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithContextDefaults(ctx),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeFormat))
+}
@@ -0,0 +1,424 @@
+package problemdetail
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// omitNewline is the opposite of the setting of SetTrailingNewline, so the zero value is the default.
+var omitNewline atomic.Bool
+
+// SetTrailingNewline sets whether the JSON writers end the body with a newline, as json.Encoder does. The default is
+// true, set it to false when the body must be byte-exact, for example to compute an ETag. It is safe for concurrent
+// use, but it is meant to be set once at program start.
+func SetTrailingNewline(enabled bool) { omitNewline.Store(!enabled) }
+
+// noEscapeHTML is the opposite of the setting of SetEscapeHTML, so the zero value is the default.
+var noEscapeHTML atomic.Bool
+
+// SetEscapeHTML sets whether the JSON writers escape the HTML characters <, > and & in strings, as json.Encoder does
+// by default. The default is true, set it to false when the consumers are not HTML contexts, so a detail or a URL
+// with & is written as it is instead of as \u0026. It is safe for concurrent use, but it is meant to be set once at
+// program start.
+func SetEscapeHTML(enabled bool) { noEscapeHTML.Store(!enabled) }
+
+// escapeHTML reports the setting of SetEscapeHTML.
+func escapeHTML() bool { return !noEscapeHTML.Load() }
+
+// marshalValue is like json.Marshal, but it escapes the HTML characters <, > and & in strings only if escapeHTML is
+// true.
+func marshalValue(v any, escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(escapeHTML)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// WithEnvelope makes the JSON writers nest the problem detail under the given member of a wrapper object, for example
+// with "error" the body is {"error":{"type":...}}. It is not standard, it is meant for the legacy consumers that expect
+// such a layout. The content type is still application/problem+json. An empty key writes the problem detail at the
+// top level, as by default.
+func WithEnvelope(key string) Option {
+	return func(pd *ProblemDetail) { pd.envelope = key }
+}
+
+// WithExtensionKeyTransform makes the JSON writers write the name of every extension member added by WithExtension,
+// and the other options that add extension members, as transformed by fn, for example from snake_case to camelCase,
+// to match the conventions of the consumers without duplicating the extension members. The standard members and the
+// fields of a type that embeds ProblemDetail are not transformed. A transformed name that is the name of a standard
+// member is rejected with ErrReservedExtension.
+func WithExtensionKeyTransform(fn func(string) string) Option {
+	return func(pd *ProblemDetail) { pd.keyTransform = fn }
+}
+
+// encodeJSON encodes the problem detail as JSON followed by a newline, the same as json.Encoder does, unless it is
+// disabled by SetTrailingNewline. Extension members are appended after the members of pd itself, and the result is
+// nested under the envelope set by WithEnvelope, if any. The HTML characters are escaped as set by SetEscapeHTML.
+func encodeJSON(pd ProblemDetailer) ([]byte, error) {
+	return encodeJSONWith(pd, escapeHTML())
+}
+
+// encodeJSONWith is like encodeJSON, but the HTML characters are escaped only if escapeHTML is true.
+func encodeJSONWith(pd ProblemDetailer, escapeHTML bool) ([]byte, error) {
+	raw, err := marshalJSONWith(pd, escapeHTML)
+	if err != nil {
+		return nil, err
+	}
+	if p := baseOf(pd); p != nil && p.envelope != "" {
+		raw = wrapJSON(p.envelope, raw, escapeHTML)
+	}
+	if omitNewline.Load() {
+		return raw, nil
+	}
+	return append(raw, '\n'), nil
+}
+
+// wrapJSON returns a JSON object with the single member key, whose value is raw.
+func wrapJSON(key string, raw []byte, escapeHTML bool) []byte {
+	name, _ := marshalValue(key, escapeHTML) // a string is always encodable.
+	buf := make([]byte, 0, len(name)+len(raw)+3)
+	buf = append(buf, '{')
+	buf = append(buf, name...)
+	buf = append(buf, ':')
+	buf = append(buf, raw...)
+	return append(buf, '}')
+}
+
+// marshalJSON encodes the problem detail as JSON, extension members are appended after the members of pd itself. The
+// HTML characters are escaped as set by SetEscapeHTML.
+func marshalJSON(pd ProblemDetailer) ([]byte, error) {
+	return marshalJSONWith(pd, escapeHTML())
+}
+
+// marshalJSONWith is like marshalJSON, but the HTML characters are escaped only if escapeHTML is true.
+func marshalJSONWith(pd ProblemDetailer, escapeHTML bool) ([]byte, error) {
+	raw, err := marshalValue(pd, escapeHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	p := baseOf(pd)
+	if p == nil || (len(p.extensions) == 0 && !p.suppressStatus && !p.statusAsString) {
+		return raw, nil
+	}
+
+	obj, err := decodeObject(raw)
+	if err != nil {
+		return nil, err
+	}
+	if p.suppressStatus {
+		obj.del("status")
+	} else if p.statusAsString {
+		if err := obj.set("status", strconv.Itoa(p.Status), escapeHTML); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(p.extensions) {
+		key := name
+		if p.keyTransform != nil {
+			key = p.keyTransform(name)
+			if _, ok := standardMembers[key]; ok {
+				return nil, fmt.Errorf("extension %q: %w: %q", name, ErrReservedExtension, key)
+			}
+		}
+		if err := obj.set(key, p.extensions[name], escapeHTML); err != nil {
+			return nil, fmt.Errorf("extension %q: %w", name, err)
+		}
+	}
+	return obj.bytes(escapeHTML), nil
+}
+
+// encodeXML encodes the problem detail as XML. Extension members are appended as child elements of the root after
+// the elements of pd itself, then the document is rewritten if the problem detail customizes its XML encoding.
+func encodeXML(pd ProblemDetailer) ([]byte, error) {
+	raw, err := xml.Marshal(pd)
+	if err != nil {
+		return nil, err
+	}
+
+	p := baseOf(pd)
+	if p == nil {
+		return raw, nil
+	}
+	if len(p.extensions) > 0 {
+		raw, err = appendXMLExtensions(raw, p.extensions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.xml.emitEmpty {
+		raw, err = appendXMLEmptyCollections(raw, pd, p.extensions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts := p.xml; opts != (xmlOptions{declaration: opts.declaration}) {
+		raw, err = rewriteXML(raw, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.xml.declaration {
+		raw = append([]byte(xml.Header), raw...)
+	}
+	return raw, nil
+}
+
+// appendXMLExtensions appends the extension members as child elements of the root element. A name that is not a valid
+// XML element name is reported as ErrXMLName, see isXMLName.
+func appendXMLExtensions(raw []byte, extensions map[string]any) ([]byte, error) {
+	end := bytes.LastIndex(raw, []byte("</"))
+	if end < 0 {
+		return nil, errors.New("xml: root element is not closed")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(raw[:end])
+	enc := xml.NewEncoder(&buf)
+	for _, name := range sortedKeys(extensions) {
+		if !isXMLName(name) {
+			return nil, fmt.Errorf("extension %q: %w", name, ErrXMLName)
+		}
+		value, err := xmlValue(extensions[name])
+		if err != nil {
+			return nil, fmt.Errorf("extension %q: %w", name, err)
+		}
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		if err := enc.EncodeElement(value, start); err != nil {
+			return nil, fmt.Errorf("extension %q: %w", name, err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.Write(raw[end:])
+	return buf.Bytes(), nil
+}
+
+// xmlValue returns the value to encode as the XML element of an extension member. A time is formatted as RFC 3339,
+// such as 2024-01-02T03:04:05Z, without the fractional seconds that encoding/xml would write. A map, a struct, or a
+// slice of them, which encoding/xml cannot encode the same way as JSON, is encoded as an xmlTree of its JSON encoding.
+func xmlValue(v any) (any, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(time.RFC3339), nil
+	case *time.Time:
+		if t != nil {
+			return t.Format(time.RFC3339), nil
+		}
+		return v, nil
+	case xml.Marshaler:
+		return v, nil
+	}
+	if !isXMLTree(reflect.ValueOf(v)) {
+		return v, nil
+	}
+
+	raw, err := marshalValue(v, false) // the tree is decoded again, so the escaping does not matter.
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return xmlTree{value: tree}, nil
+}
+
+// isXMLTree reports whether v is a map, a struct, or a slice of them, which are encoded as nested elements.
+func isXMLTree(v reflect.Value) bool {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct:
+		return true
+	case reflect.Slice, reflect.Array:
+		elem := v.Type().Elem()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		return elem.Kind() == reflect.Map || elem.Kind() == reflect.Struct || elem.Kind() == reflect.Interface
+	}
+	return false
+}
+
+// xmlTree encodes the decoded JSON value of an extension member as nested XML elements: the members of an object
+// are child elements, ordered by name, the items of an array repeat the element, and other values are its text. A
+// null is an empty element.
+type xmlTree struct {
+	value any
+}
+
+// MarshalXML implements xml.Marshaler.
+func (t xmlTree) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	switch v := t.value.(type) {
+	case []any:
+		for _, item := range v {
+			if err := enc.EncodeElement(xmlTree{value: item}, start); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(v) {
+			if !isXMLName(name) {
+				return fmt.Errorf("%w: %q", ErrXMLName, name)
+			}
+			child := xml.StartElement{Name: xml.Name{Local: name}}
+			if err := enc.EncodeElement(xmlTree{value: v[name]}, child); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case nil:
+		return enc.EncodeElement("", start)
+	default:
+		return enc.EncodeElement(fmt.Sprint(v), start)
+	}
+}
+
+// isXMLName reports whether name matches the Name production of XML, so it can be written as an element name. For
+// example, "2fa" or "user id" cannot.
+//
+// ref: https://www.w3.org/TR/xml/#NT-Name
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !isXMLNameStartChar(r) && (i == 0 || !isXMLNameChar(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isXMLNameStartChar reports whether r matches the NameStartChar production of XML.
+func isXMLNameStartChar(r rune) bool {
+	switch {
+	case r == ':' || r == '_' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z':
+		return true
+	case 0xC0 <= r && r <= 0xD6, 0xD8 <= r && r <= 0xF6, 0xF8 <= r && r <= 0x2FF, 0x370 <= r && r <= 0x37D,
+		0x37F <= r && r <= 0x1FFF, 0x200C <= r && r <= 0x200D, 0x2070 <= r && r <= 0x218F,
+		0x2C00 <= r && r <= 0x2FEF, 0x3001 <= r && r <= 0xD7FF, 0xF900 <= r && r <= 0xFDCF,
+		0xFDF0 <= r && r <= 0xFFFD, 0x10000 <= r && r <= 0xEFFFF:
+		return true
+	}
+	return false
+}
+
+// isXMLNameChar reports whether r matches the NameChar production of XML, other than a NameStartChar.
+func isXMLNameChar(r rune) bool {
+	return r == '-' || r == '.' || '0' <= r && r <= '9' || r == 0xB7 ||
+		0x300 <= r && r <= 0x36F || 0x203F <= r && r <= 0x2040
+}
+
+// member is a single name/value pair of a JSON object.
+type member struct {
+	name  string
+	value json.RawMessage
+}
+
+// object is a JSON object that keeps the order of its members, so the standard members stay in front.
+type object []member
+
+// decodeObject decodes a JSON object while keeping the order of its members.
+func decodeObject(data []byte) (object, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("json: expected an object, got %v", tok)
+	}
+
+	var obj object
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		obj = append(obj, member{name: tok.(string), value: value})
+	}
+	return obj, nil
+}
+
+// set replaces the value of the named member, or appends it if the object does not have such member. The HTML
+// characters of the value are escaped only if escapeHTML is true.
+func (o *object) set(name string, v any, escapeHTML bool) error {
+	value, err := marshalValue(v, escapeHTML)
+	if err != nil {
+		return err
+	}
+	for i := range *o {
+		if (*o)[i].name == name {
+			(*o)[i].value = value
+			return nil
+		}
+	}
+	*o = append(*o, member{name: name, value: value})
+	return nil
+}
+
+// del removes the named member, if any.
+func (o *object) del(name string) {
+	for i := range *o {
+		if (*o)[i].name == name {
+			*o = append((*o)[:i], (*o)[i+1:]...)
+			return
+		}
+	}
+}
+
+// bytes returns the JSON encoding of the object, the HTML characters of the names are escaped only if escapeHTML is
+// true.
+func (o object) bytes(escapeHTML bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := marshalValue(m.name, escapeHTML) // marshaling a string never fails.
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(m.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// sortedKeys returns the keys of m in ascending order, so the output is deterministic.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package problemdetail
+
+import (
+	"errors"
+	"net/http"
+)
+
+// WithCauses attaches the errors that caused the problem, for example the sub-errors of an aggregated operation, so
+// errors.Is and errors.As traverse all of them through ProblemDetail.Unwrap, as they do for errors.Join. The causes are
+// not written to the body. Nil errors are skipped, and the option can be given several times to add more causes.
+func WithCauses(errs ...error) Option {
+	return func(pd *ProblemDetail) {
+		for _, err := range errs {
+			if err != nil {
+				pd.causes = append(pd.causes, err)
+			}
+		}
+	}
+}
+
+// Unwrap returns the causes attached by WithCauses, it is the multi-error form of Unwrap that errors.Is and errors.As
+// understand.
+func (p *ProblemDetail) Unwrap() []error { return p.causes }
+
+// FromError converts err into a problem detail:
+//
+//   - if err, or any error in its chain, is a *ProblemDetail, such as one created by Errorf, it is returned as it is,
+//     with the status 500 (Internal Server Error) if it has none;
+//   - if err matches an error registered by RegisterError, an untyped problem detail with the registered status and
+//     the error message as detail is returned;
+//   - otherwise, an untyped 500 (Internal Server Error) problem detail is returned, without detail, so the message
+//     of an unexpected error is not leaked to the client.
+//
+// The instance is set by WithInstanceFromError, and the validation level is LStandard. A nil error returns nil.
+func FromError(err error) *ProblemDetail {
+	if err == nil {
+		return nil
+	}
+
+	var pd *ProblemDetail
+	if errors.As(err, &pd) {
+		if pd.Status == 0 {
+			pd.WriteStatus(http.StatusInternalServerError)
+		}
+		return pd
+	}
+
+	opts := []Option{WithValidateLevel(LStandard), WithInstanceFromError(err)}
+	status, ok := statusFromError(err)
+	if ok {
+		opts = append(opts, WithDetail(err.Error()))
+	} else {
+		status = http.StatusInternalServerError
+	}
+	pd = New(Untyped, opts...)
+	pd.WriteStatus(status)
+	return pd
+}
+
+// WriteError converts err with FromError and writes it with Write, in the format accepted by the request and with
+// the status code of the converted problem detail. If err, or any error in its chain, is a ProblemDetailer, such as a
+// type that embeds ProblemDetail, it is written as it is, so its own members are kept, with the status 500 (Internal
+// Server Error) if it has none.
+//
+// A nil error is rejected with an error, since there is no problem to write.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) error {
+	if err == nil {
+		return errors.New("WriteError: error is nil")
+	}
+
+	pd, status := problemFromError(err)
+	return Write(w, r, pd, status)
+}
+
+// problemFromError returns the problem detail that WriteError writes for err, and its status code. If err, or any
+// error in its chain, is a ProblemDetailer, it is returned as it is, otherwise err is converted with FromError.
+func problemFromError(err error) (ProblemDetailer, int) {
+	var pd ProblemDetailer
+	if !errors.As(err, &pd) {
+		pd = FromError(err)
+	}
+	return pd, statusOf(pd)
+}
+
+// statusOf returns the status code of the problem detail, 500 (Internal Server Error) if it has none.
+func statusOf(pd ProblemDetailer) int {
+	if p := baseOf(pd); p != nil && p.Status != 0 {
+		return p.Status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,114 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+var errProductNotFound = errors.New("product not found")
+
+func TestFromError(t *testing.T) {
+	problemdetail.RegisterError(errProductNotFound, 404)
+
+	pd := problemdetail.FromError(fmt.Errorf("get product 42: %w", errProductNotFound))
+	expectTrue(t, pd.Type == problemdetail.Untyped)
+	expectTrue(t, pd.Title == "Not Found")
+	expectTrue(t, pd.Status == 404)
+	expectTrue(t, pd.Detail == "get product 42: product not found")
+
+	pd = problemdetail.FromError(fmt.Errorf("get product 42: %w", &OrderNotFoundError{ID: "/orders/42"}))
+	expectTrue(t, pd.Status == 500)
+	expectTrue(t, pd.Detail == "")
+	expectTrue(t, pd.Instance == "/orders/42")
+
+	typed := problemdetail.Errorf("https://example.com/probs/out-of-credit", 403, "balance is %d", 30)
+	pd = problemdetail.FromError(fmt.Errorf("checkout: %w", typed))
+	expectTrue(t, pd == typed)
+
+	expectTrue(t, problemdetail.FromError(nil) == nil)
+}
+
+// FieldsError is an error that cannot be compared, since it holds a slice.
+type FieldsError struct{ Fields []string }
+
+func (e FieldsError) Error() string { return "invalid fields: " + strings.Join(e.Fields, ", ") }
+
+// Is reports whether target is a FieldsError, regardless of its fields.
+func (e FieldsError) Is(target error) bool {
+	_, ok := target.(FieldsError)
+	return ok
+}
+
+func TestRegisterError_UncomparableTarget(t *testing.T) {
+	problemdetail.RegisterError(FieldsError{Fields: []string{"name"}}, 422)
+	problemdetail.RegisterError(FieldsError{Fields: []string{"email"}}, 422)
+
+	pd := problemdetail.FromError(fmt.Errorf("create user: %w", FieldsError{Fields: []string{"age"}}))
+	expectTrue(t, pd.Status == 422)
+	expectTrue(t, pd.Title == "Unprocessable Entity")
+}
+
+func TestWriteError(t *testing.T) {
+	problemdetail.RegisterError(errProductNotFound, 404)
+
+	req := httptest.NewRequest("GET", "/products/42", nil)
+	req.Header.Set("Accept", "application/problem+xml")
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteError(rec, req, fmt.Errorf("get product 42: %w", errProductNotFound))
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Code == 404)
+
+	rawExp := `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Not Found</title><status>404</status><detail>get product 42: product not found</detail></problem>`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == rawExp)
+
+	data := &BalanceProblemDetail{
+		ProblemDetail: problemdetail.New("https://example.com/probs/out-of-credit",
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithTitle("You do not have enough credit."),
+		),
+		Balance: 30,
+	}
+	data.WriteStatus(403)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteError(rec, httptest.NewRequest("GET", "/", nil), fmt.Errorf("checkout: %w", data))
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Code == 403)
+
+	rawExp = `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"balance":30,"accounts":null}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == rawExp)
+
+	err = problemdetail.WriteError(httptest.NewRecorder(), req, nil)
+	expectTrue(t, err != nil)
+}
+
+func TestWithCauses(t *testing.T) {
+	errDebit := errors.New("debit failed")
+	errCredit := fmt.Errorf("credit account 67890: %w", errProductNotFound)
+
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCauses(errDebit, nil),
+		problemdetail.WithCauses(errCredit),
+	)
+	expectTrue(t, len(data.Unwrap()) == 2)
+
+	var err error = fmt.Errorf("transfer: %w", data)
+	expectTrue(t, errors.Is(err, errDebit))
+	expectTrue(t, errors.Is(err, errProductNotFound))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrIO))
+
+	rec := httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 500)
+	expectTrue(t, err == nil)
+	expRaw := `{"type":"about:blank","title":"Internal Server Error","status":500}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	expectTrue(t, problemdetail.New(problemdetail.Untyped).Unwrap() == nil)
+}
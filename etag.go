@@ -0,0 +1,82 @@
+package problemdetail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// WithETag makes the writers set the ETag header to a hash of the encoded body, so a repeated static problem, such as
+// a 404 page, can be cached by a CDN. The hash only depends on the body, identical bodies have identical tags.
+//
+// With Write, if the If-None-Match header of the request matches the tag, 304 (Not Modified) is written without body.
+// As RFC 9110 requires, the header is ignored unless the status code is 2xx, so a 404 or a 5xx is always written.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9110#section-13.2.1
+func WithETag() Option {
+	return func(pd *ProblemDetail) { pd.etag = true }
+}
+
+// WithVersionConflict adds the expectedVersion and actualVersion extension members to a problem of an optimistic
+// concurrency control, usually 409 (Conflict) or 412 (Precondition Failed), and makes the writers set the ETag header
+// to the actual version, so a client can refetch or retry the update without another round trip. The version is
+// quoted as an entity tag unless it already is one. WithETag, if set, overrides the ETag header.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9110#section-13.1.1
+func WithVersionConflict(expected, actual string) Option {
+	return func(pd *ProblemDetail) {
+		WithExtension("expectedVersion", expected)(pd)
+		WithExtension("actualVersion", actual)(pd)
+		tag := entityTag(actual)
+		pd.headers = append(pd.headers, func(h http.Header, _ *ProblemDetail) { h.Set("ETag", tag) })
+	}
+}
+
+// entityTag returns the version as an entity tag, it is quoted unless it already is a strong or a weak entity tag.
+func entityTag(version string) string {
+	if strings.HasPrefix(strings.TrimPrefix(version, "W/"), `"`) {
+		return version
+	}
+	return `"` + version + `"`
+}
+
+// notModified sets the ETag header if the problem detail has WithETag, and reports whether the request has a matching
+// If-None-Match header. The precondition is only evaluated for a 2xx status code.
+func notModified(w http.ResponseWriter, r *http.Request, pd ProblemDetailer, code int, body []byte) bool {
+	tag, ok := etagOf(pd, body)
+	if !ok {
+		return false
+	}
+	w.Header().Set("ETag", tag)
+	if r == nil || code < 200 || code > 299 {
+		return false
+	}
+	return matchETag(r.Header.Values("If-None-Match"), tag)
+}
+
+// etagOf returns the entity tag of the encoded body, it returns false if the problem detail does not have WithETag.
+func etagOf(pd ProblemDetailer, body []byte) (string, bool) {
+	p := baseOf(pd)
+	if p == nil || !p.etag {
+		return "", false
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, true
+}
+
+// matchETag reports whether one of the entity tags of the If-None-Match header matches tag, using the weak
+// comparison.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9110#section-13.1.2
+func matchETag(header []string, tag string) bool {
+	for _, line := range header {
+		for _, candidate := range strings.Split(line, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWrite_WithETag(t *testing.T) {
+	newPD := func() *problemdetail.ProblemDetail {
+		return problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithETag(),
+		)
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, newPD(), 404)
+	expectTrue(t, err == nil)
+	etag := rec.Header().Get("ETag")
+	expectTrue(t, len(etag) == 34)
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	rec = httptest.NewRecorder()
+	err = problemdetail.Write(rec, req, newPD(), 404)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Code == 404)
+	expectTrue(t, rec.Header().Get("ETag") == etag)
+
+	// a precondition is ignored for a status code other than 2xx.
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	rec = httptest.NewRecorder()
+	err = problemdetail.Write(rec, req, newPD(), 404)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Code == 404)
+	expectTrue(t, rec.Body.Len() > 0)
+	expectTrue(t, rec.Header().Get("ETag") == etag)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.Write(rec, req, newPD(), 200)
+	expectTrue(t, err == nil)
+	etag = rec.Header().Get("ETag")
+
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	rec = httptest.NewRecorder()
+	err = problemdetail.Write(rec, req, newPD(), 200)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Code == 304)
+	expectTrue(t, rec.Body.Len() == 0)
+	expectTrue(t, rec.Header().Get("ETag") == etag)
+	expectTrue(t, rec.Header().Get("Content-Type") == "")
+}
+
+func TestDryRunJSON_WithETag(t *testing.T) {
+	newPD := func() *problemdetail.ProblemDetail {
+		return problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithETag(),
+		)
+	}
+
+	body, header, err := problemdetail.DryRunJSON(newPD(), 404)
+	expectTrue(t, err == nil)
+
+	rec := httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, newPD(), 404)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Body.String() == string(body))
+	expectTrue(t, header.Get("ETag") != "")
+	expectTrue(t, header.Get("ETag") == rec.Header().Get("ETag"))
+}
+
+func TestWriteJSON_WithoutETag(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 404)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("ETag") == "")
+}
+
+func TestWriteJSON_WithVersionConflict(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithVersionConflict("3", "5"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 412)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("ETag") == `"5"`)
+
+	expRaw := `{"type":"about:blank","title":"Precondition Failed","status":412,"actualVersion":"5","expectedVersion":"3"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	data = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithVersionConflict(`"a1"`, `W/"b2"`),
+	)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 409)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("ETag") == `W/"b2"`)
+}
@@ -0,0 +1,211 @@
+package problemdetail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"text/template"
+)
+
+// maxExtensions is the limit set by SetMaxExtensions, 0 means unlimited.
+var maxExtensions atomic.Int64
+
+// SetMaxExtensions sets the maximum number of extension members of a problem detail, a problem detail with more of
+// them is reported as ErrTooManyExtensions when it is validated, whatever its validation level. It is a safety valve
+// against a loop that adds extension members by mistake, so the bodies do not bloat. A non-positive n, the default,
+// means unlimited. It is safe for concurrent use, but it is meant to be set once at program start.
+func SetMaxExtensions(n int) {
+	maxExtensions.Store(int64(max(n, 0)))
+}
+
+// WithExtension adds an extension member to the ProblemDetail. Extension members are written next to the standard
+// members, an existing member with the same name is replaced. The name of a standard member is rejected with
+// ErrReservedExtension when the problem detail is validated.
+//
+// ref: https://tools.ietf.org/html/rfc7807#section-3.2
+func WithExtension(name string, value any) Option {
+	return func(pd *ProblemDetail) {
+		if _, ok := standardMembers[name]; ok {
+			pd.errs = append(pd.errs, fmt.Errorf("%w: %q", ErrReservedExtension, name))
+			return
+		}
+		if pd.extensions == nil {
+			pd.extensions = make(map[string]any)
+		}
+		pd.extensions[name] = value
+	}
+}
+
+// WithContextValues adds the values carried by ctx as extension members, so request-scoped metadata, such as the
+// request id, the user id or the tenant, is pulled into the problem detail in one go. The keys map the extension names
+// to the context keys, for example:
+//
+//	WithContextValues(ctx, map[string]any{"requestId": requestIDKey{}, "tenant": tenantKey{}})
+//
+// A context key without a value in ctx is skipped. Like WithExtension, the names of the standard members are rejected
+// with ErrReservedExtension.
+func WithContextValues(ctx context.Context, keys map[string]any) Option {
+	return func(pd *ProblemDetail) {
+		for _, name := range sortedKeys(keys) {
+			if value := ctx.Value(keys[name]); value != nil {
+				WithExtension(name, value)(pd)
+			}
+		}
+	}
+}
+
+// WithExtensionStruct adds the JSON members of v as extension members, so an existing type can be reused as the
+// extension payload without embedding ProblemDetail. The value must be a struct or a map, or a pointer to one of them,
+// otherwise ErrExtensionStruct is returned when the problem detail is validated. Like WithExtension, the names of the
+// standard members are rejected with ErrReservedExtension.
+func WithExtensionStruct(v any) Option {
+	return func(pd *ProblemDetail) {
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		if kind := rv.Kind(); kind != reflect.Struct && kind != reflect.Map {
+			pd.errs = append(pd.errs, fmt.Errorf("%w: %T", ErrExtensionStruct, v))
+			return
+		}
+
+		raw, err := json.Marshal(v)
+		if err != nil {
+			pd.errs = append(pd.errs, fmt.Errorf("extension struct: %w", err))
+			return
+		}
+
+		var members map[string]any
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber() // keep the numbers as they are encoded by v.
+		if err := dec.Decode(&members); err != nil {
+			pd.errs = append(pd.errs, fmt.Errorf("extension struct: %w", err))
+			return
+		}
+
+		for _, name := range sortedKeys(members) {
+			WithExtension(name, members[name])(pd)
+		}
+	}
+}
+
+// Extensions returns a copy of the extension members added by options or decoded by ReadFrom.
+func (p *ProblemDetail) Extensions() map[string]any { return maps.Clone(p.extensions) }
+
+// Fields returns the standard members and the extension members of the problem detail as a flat map, so it can be
+// given to any structured logger, such as zap.Any in a loop or zerolog's Fields, without coupling the package to one.
+// The empty standard members, a zero status and the nil extension members are omitted. A new map is returned on every
+// call, so the caller may modify it.
+func (p *ProblemDetail) Fields() map[string]any {
+	fields := make(map[string]any, 5+len(p.extensions))
+	for name, value := range map[string]string{
+		"type":     p.Type,
+		"title":    p.Title,
+		"detail":   p.Detail,
+		"instance": p.Instance,
+	} {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	if p.Status != 0 {
+		fields["status"] = p.Status
+	}
+	for name, value := range p.extensions {
+		if value != nil {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// WithDetailTemplate sets a text/template that is rendered against the extension members at write time to produce
+// ProblemDetail.Detail. For example, "Your balance is {{.balance}}, but that costs {{.cost}}.".
+//
+// If the template refers to a missing extension member, the writers return ErrTemplateKey.
+func WithDetailTemplate(tmpl string) Option {
+	return func(pd *ProblemDetail) { pd.detailTemplate = tmpl }
+}
+
+// validateExtensions ensures that every extension member can be encoded as JSON, so values such as NaN or channels are
+// caught by Validate instead of by the writers. The error names the offending members.
+func (p *ProblemDetail) validateExtensions() error {
+	if !p.flags.has(LExtensionFormat) {
+		return nil
+	}
+
+	var errs []error
+	for _, name := range sortedKeys(p.extensions) {
+		if _, err := json.Marshal(p.extensions[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q: %w", ErrExtensionNotSerializable, name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateEmbeddingFields is like validateExtensions for the fields of a type that embeds ProblemDetail, which are
+// written as extension members too. A type that implements json.Marshaler is not checked, its encoding is its own.
+func validateEmbeddingFields(pd ProblemDetailer) error {
+	p := baseOf(pd)
+	if p == nil || !p.flags.has(LExtensionFormat) {
+		return nil
+	}
+	if _, ok := pd.(*ProblemDetail); ok {
+		return nil
+	}
+	if _, ok := pd.(json.Marshaler); ok {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(pd))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := jsonFieldName(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+		if _, err := json.Marshal(v.Field(i).Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q: %w", ErrExtensionNotSerializable, name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateExtensionCount ensures that the number of extension members does not exceed the limit set by
+// SetMaxExtensions.
+func (p *ProblemDetail) validateExtensionCount() error {
+	limit := maxExtensions.Load()
+	if limit == 0 || int64(len(p.extensions)) <= limit {
+		return nil
+	}
+	return fmt.Errorf("%w: %d, the maximum is %d", ErrTooManyExtensions, len(p.extensions), limit)
+}
+
+// renderDetail renders the detail template, if any, into ProblemDetail.Detail.
+func (p *ProblemDetail) renderDetail() error {
+	if p.detailTemplate == "" {
+		return nil
+	}
+
+	tmpl, err := template.New("detail").Option("missingkey=error").Parse(p.detailTemplate)
+	if err != nil {
+		return fmt.Errorf("detail template: %w", err)
+	}
+
+	var sb strings.Builder
+	data := p.extensions
+	if data == nil {
+		data = map[string]any{}
+	}
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return errors.Join(ErrTemplateKey, err)
+	}
+	p.Detail = sb.String()
+	return nil
+}
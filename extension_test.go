@@ -0,0 +1,315 @@
+package problemdetail_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSON_WithExtensionMembers(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithExtension("accounts", []string{"/account/12345", "/account/67890"}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"accounts":["/account/12345","/account/67890"],"balance":30}`
+	gotRaw := strings.TrimSpace(rec.Body.String())
+
+	expectTrue(t, gotRaw == expRaw)
+	expectTrue(t, rec.Code == 403)
+}
+
+func TestWriteXML_WithExtensionMembers(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithExtension("accounts", []string{"/account/12345", "/account/67890"}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/probs/out-of-credit</type><title>You do not have enough credit.</title><status>403</status><accounts>/account/12345</accounts><accounts>/account/67890</accounts><balance>30</balance></problem>`
+	rawGot := strings.TrimSpace(rec.Body.String())
+
+	expectTrue(t, rawGot == rawExp)
+	expectTrue(t, rec.Code == 403)
+}
+
+func TestWriteJSON_WithDetailTemplate(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard|problemdetail.LDetailRequired),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithExtension("cost", 50),
+		problemdetail.WithDetailTemplate("Your current balance is {{.balance}}, but that costs {{.cost}}."),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"detail":"Your current balance is 30, but that costs 50.","balance":30,"cost":50}`
+	gotRaw := strings.TrimSpace(rec.Body.String())
+
+	expectTrue(t, gotRaw == expRaw)
+	expectTrue(t, data.Detail == "Your current balance is 30, but that costs 50.")
+}
+
+func TestWriteJSON_WithDetailTemplateMissingKey(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithDetailTemplate("Your current balance is {{.balance}}, but that costs {{.cost}}."),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTemplateKey))
+	expectTrue(t, rec.Body.Len() == 0)
+}
+
+func TestWriteJSON_WithExtensionStruct(t *testing.T) {
+	type balance struct {
+		Balance  int32    `json:"balance"`
+		Accounts []string `json:"accounts"`
+	}
+
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtensionStruct(&balance{Balance: 30, Accounts: []string{"/account/12345"}}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"accounts":["/account/12345"],"balance":30}`
+	gotRaw := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, gotRaw == expRaw)
+}
+
+func TestWriteJSON_WithExtensionStructReservedName(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtensionStruct(map[string]any{"status": 200, "balance": 30}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrReservedExtension))
+	expectTrue(t, rec.Body.Len() == 0)
+}
+
+func TestWriteJSON_WithExtensionStructNotStruct(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(0),
+		problemdetail.WithExtensionStruct([]int{1, 2, 3}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrExtensionStruct))
+}
+
+func TestProblemDetail_ValidateExtensionNotSerializable(t *testing.T) {
+	pd := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+		problemdetail.WithInstance("/account/12345/abc"),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", math.NaN()),
+		problemdetail.WithExtension("updates", make(chan int)),
+		problemdetail.WithExtension("cost", 50),
+	)
+	pd.WriteStatus(403)
+
+	err := pd.Validate()
+	expectTrue(t, errors.Is(err, problemdetail.ErrExtensionNotSerializable))
+	expectTrue(t, strings.Contains(err.Error(), `"balance"`))
+	expectTrue(t, strings.Contains(err.Error(), `"updates"`))
+	expectTrue(t, !strings.Contains(err.Error(), `"cost"`))
+
+	standard := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("balance", math.NaN()),
+	)
+	standard.WriteStatus(403)
+	expectTrue(t, standard.Validate() == nil)
+}
+
+// MeasuredProblemDetail is a sample problem detail with extension fields that may not be encodable.
+type MeasuredProblemDetail struct {
+	*problemdetail.ProblemDetail
+	Ratio   float64  `json:"ratio"`
+	Updates any      `json:"updates,omitempty"`
+	Cost    int      `json:"cost"`
+	Ignored chan int `json:"-"`
+}
+
+func TestWriteJSON_EmbeddingFieldNotSerializable(t *testing.T) {
+	data := &MeasuredProblemDetail{
+		ProblemDetail: problemdetail.New(
+			"https://example.com/probs/out-of-credit",
+			problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+			problemdetail.WithInstance("/account/12345/abc"),
+			problemdetail.WithTitle("You do not have enough credit."),
+		),
+		Ratio:   math.NaN(),
+		Updates: make(chan int),
+		Cost:    50,
+		Ignored: make(chan int),
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrExtensionNotSerializable))
+	expectTrue(t, errors.Is(err, problemdetail.ErrValidation))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrMarshal))
+	expectTrue(t, strings.Contains(err.Error(), `"ratio"`))
+	expectTrue(t, strings.Contains(err.Error(), `"updates"`))
+	expectTrue(t, !strings.Contains(err.Error(), `"cost"`))
+	expectTrue(t, rec.Body.Len() == 0)
+
+	data.Ratio, data.Updates = math.Inf(1), nil
+	problemdetail.WithValidateLevel(problemdetail.LStandard)(data.ProblemDetail)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrMarshal))
+
+	data.Ratio = 0.5
+	problemdetail.WithValidateLevel(problemdetail.LStrict)(data.ProblemDetail)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+}
+
+type (
+	requestIDKey struct{}
+	tenantKey    struct{}
+	userIDKey    struct{}
+)
+
+func TestWriteJSON_WithContextValues(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithContextValues(ctx, map[string]any{
+			"requestId": requestIDKey{},
+			"tenant":    tenantKey{},
+			"userId":    userIDKey{},
+		}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `{"type":"about:blank","title":"Forbidden","status":403,"requestId":"req-1","tenant":"acme"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == rawExp)
+}
+
+func TestWriteJSON_WithExtensionKeyTransform(t *testing.T) {
+	snakeToCamel := func(name string) string {
+		parts := strings.Split(name, "_")
+		for i := 1; i < len(parts); i++ {
+			if parts[i] != "" {
+				parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+			}
+		}
+		return strings.Join(parts, "")
+	}
+
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("current_balance", 30),
+		problemdetail.WithExtension("account_ids", []string{"/account/12345"}),
+		problemdetail.WithExtensionKeyTransform(snakeToCamel),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `{"type":"about:blank","title":"Forbidden","status":403,"accountIds":["/account/12345"],"currentBalance":30}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == rawExp)
+
+	data = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("Detail", "shadowed"),
+		problemdetail.WithExtensionKeyTransform(strings.ToLower),
+	)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrReservedExtension))
+}
+
+func TestSetMaxExtensions(t *testing.T) {
+	problemdetail.SetMaxExtensions(2)
+	t.Cleanup(func() { problemdetail.SetMaxExtensions(0) })
+
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("a", 1),
+		problemdetail.WithExtension("b", 2),
+	)
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 400)
+	expectTrue(t, err == nil)
+
+	problemdetail.WithExtension("c", 3)(data)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 400)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTooManyExtensions))
+	expectTrue(t, errors.Is(err, problemdetail.ErrValidation))
+	expectTrue(t, rec.Body.Len() == 0)
+
+	problemdetail.SetMaxExtensions(-1)
+	err = data.Validate()
+	expectTrue(t, err == nil)
+}
+
+func TestProblemDetail_Fields(t *testing.T) {
+	data := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithExtension("accounts", nil),
+	)
+
+	fields := data.Fields()
+	expectTrue(t, len(fields) == 3)
+	expectTrue(t, fields["type"] == "https://example.com/probs/out-of-credit")
+	expectTrue(t, fields["title"] == "You do not have enough credit.")
+	expectTrue(t, fields["balance"] == 30)
+
+	data.WriteStatus(403)
+	fields = data.Fields()
+	expectTrue(t, len(fields) == 4 && fields["status"] == 403)
+
+	fields["balance"] = 0
+	expectTrue(t, data.Extensions()["balance"] == 30)
+}
@@ -1,3 +1,5 @@
 module github.com/josestg/problemdetail
 
 go 1.21.3
+
+require github.com/google/go-cmp v0.7.0
@@ -3,5 +3,8 @@
 # ensure the execution will stop if any command fails (returns non-zero value)
 set -e -o pipefail
 
-echo "execute go test"
-go test -race -short -timeout 60s ./...
\ No newline at end of file
+# problemfasthttp is a module of its own, so the core module does not depend on fasthttp.
+for module in . problemfasthttp; do
+  echo "execute go test in ${module}"
+  (cd "${module}" && go test -race -short -timeout 60s ./...)
+done
\ No newline at end of file
@@ -0,0 +1,165 @@
+package problemdetail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WithCORSAllowOrigin makes the writers set the Access-Control-Allow-Origin header to origin, and add Origin to the
+// Vary header, so a script of a cross-origin page can read the problem detail. An empty origin is a no-op.
+//
+// ref: https://fetch.spec.whatwg.org/#http-access-control-allow-origin
+func WithCORSAllowOrigin(origin string) Option {
+	return func(pd *ProblemDetail) {
+		if origin == "" {
+			return
+		}
+		pd.headers = append(pd.headers, func(h http.Header, _ *ProblemDetail) {
+			h.Set("Access-Control-Allow-Origin", origin)
+			addVary(h, "Origin")
+		})
+	}
+}
+
+// WithNoSniff makes the writers set the X-Content-Type-Options header to nosniff, so browsers do not MIME-sniff the
+// problem detail into an executable content type.
+//
+// ref: https://fetch.spec.whatwg.org/#x-content-type-options-header
+func WithNoSniff() Option {
+	return func(pd *ProblemDetail) {
+		pd.headers = append(pd.headers, func(h http.Header, _ *ProblemDetail) {
+			h.Set("X-Content-Type-Options", "nosniff")
+		})
+	}
+}
+
+// WithDedupKey adds the dedupKey extension member and makes the writers echo it in the X-Dedup-Key header. A server
+// sets a stable key for the same logical problem, so a client can collapse the notifications of retried requests.
+// An empty key is a no-op.
+func WithDedupKey(key string) Option {
+	return func(pd *ProblemDetail) {
+		if key == "" {
+			return
+		}
+		WithExtension("dedupKey", key)(pd)
+		pd.headers = append(pd.headers, func(h http.Header, _ *ProblemDetail) { h.Set("X-Dedup-Key", key) })
+	}
+}
+
+// WithAllowedMethods makes the writers set the Allow header to the given methods when the status code is 405 (Method
+// Not Allowed), as RFC 9110 requires for such a response. For any other status code the option is ignored, so the same
+// problem detail can be written with several status codes. No methods is a no-op.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9110#section-10.2.1
+func WithAllowedMethods(methods ...string) Option {
+	return func(pd *ProblemDetail) {
+		if len(methods) == 0 {
+			return
+		}
+		allow := strings.Join(methods, ", ")
+		pd.headers = append(pd.headers, func(h http.Header, p *ProblemDetail) {
+			if p.Status == http.StatusMethodNotAllowed {
+				h.Set("Allow", allow)
+			}
+		})
+	}
+}
+
+// WithNonce adds the nonce extension member, a random 128-bit value encoded as hex, and makes the writers echo it in
+// the Nonce header, so an audit trail can detect a replayed or tampered response. Unlike a request or trace id, it is
+// unique to the response: a new nonce is generated every time the problem detail is written, so a problem detail
+// reused across requests, such as a catalog entry, never sends the same one twice. It uses crypto/rand, a failure to
+// read random bytes is returned by the writers.
+func WithNonce() Option {
+	return func(pd *ProblemDetail) {
+		pd.nonce = true
+		pd.headers = append(pd.headers, func(h http.Header, p *ProblemDetail) {
+			if nonce, ok := p.extensions["nonce"].(string); ok {
+				h.Set("Nonce", nonce)
+			}
+		})
+	}
+}
+
+// renewNonce sets the nonce extension member to a new random value if the problem detail has WithNonce.
+func (p *ProblemDetail) renewNonce() error {
+	if !p.nonce {
+		return nil
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Errorf("nonce: %w", err)
+	}
+	WithExtension("nonce", hex.EncodeToString(b[:]))(p)
+	return nil
+}
+
+// WithDocHref adds the docHref extension member and makes the writers add a Link header with the "help" relation, so
+// clients can find the human documentation of the problem. It is independent from ProblemDetail.Type, which can be an
+// opaque identifier, such as a tag URI, that is not dereferenceable. An invalid URL is reported as ErrDocHrefFormat
+// when the problem detail is validated.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc8288
+func WithDocHref(href string) Option {
+	return func(pd *ProblemDetail) {
+		if _, err := url.Parse(href); err != nil || href == "" || strings.ContainsAny(href, "<>") {
+			pd.errs = append(pd.errs, fmt.Errorf("%w: %q", ErrDocHrefFormat, href))
+			return
+		}
+		WithExtension("docHref", href)(pd)
+		link := "<" + href + `>; rel="help"`
+		pd.headers = append(pd.headers, func(h http.Header, _ *ProblemDetail) {
+			for _, value := range h.Values("Link") {
+				if value == link {
+					return
+				}
+			}
+			h.Add("Link", link)
+		})
+	}
+}
+
+// managedHeaders are the headers that the writers and the options set, which are deleted by WithScrubHeaders. The
+// list headers that are merged with the values of the application, Link and Vary, are not managed.
+var managedHeaders = []string{
+	"Access-Control-Allow-Origin",
+	"Allow",
+	"Content-Language",
+	"Content-Type",
+	"Deprecation",
+	"ETag",
+	"Nonce",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"Retry-After",
+	"Severity",
+	"Sunset",
+	"X-Content-Type-Options",
+	"X-Dedup-Key",
+}
+
+// WithScrubHeaders makes the writers delete the headers they manage, such as Retry-After, ETag or the rate limit
+// headers, before setting the ones of the problem detail. So a problem re-emitted by an error middleware, after the
+// handler or an earlier attempt already set some of them, does not carry stale or doubled headers. The Link and Vary
+// headers are kept, their values are merged instead.
+func WithScrubHeaders() Option {
+	return func(pd *ProblemDetail) { pd.scrubHeaders = true }
+}
+
+// addVary adds the field name to the Vary header, unless it is already listed.
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
@@ -0,0 +1,215 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSON_WithCORSAllowOrigin(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCORSAllowOrigin("https://app.example.com"),
+	)
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Vary", "Accept-Encoding, origin")
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Access-Control-Allow-Origin") == "https://app.example.com")
+	expectTrue(t, len(rec.Header().Values("Vary")) == 1)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Vary") == "Origin")
+}
+
+func TestWriteJSON_WithCORSAllowOriginEmpty(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCORSAllowOrigin(""),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Access-Control-Allow-Origin") == "")
+	expectTrue(t, rec.Header().Get("Vary") == "")
+}
+
+func TestWriteJSON_WithDedupKey(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDedupKey("payment-declined-12345"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 402)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Payment Required","status":402,"dedupKey":"payment-declined-12345"}`)
+	expectTrue(t, rec.Header().Get("X-Dedup-Key") == "payment-declined-12345")
+
+	data = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDedupKey(""),
+	)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 402)
+	expectTrue(t, err == nil)
+	expectTrue(t, len(data.Extensions()) == 0)
+	expectTrue(t, rec.Header().Get("X-Dedup-Key") == "")
+}
+
+func TestWriteJSON_WithDocHref(t *testing.T) {
+	data := problemdetail.New("tag:example.com,2024:out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithDocHref("https://docs.example.com/errors/out-of-credit"),
+	)
+
+	rec := httptest.NewRecorder()
+	rec.Header().Add("Link", `</style.css>; rel="preload"`)
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"tag:example.com,2024:out-of-credit","title":"You do not have enough credit.","status":403,"docHref":"https://docs.example.com/errors/out-of-credit"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	links := rec.Header().Values("Link")
+	expectTrue(t, len(links) == 2)
+	expectTrue(t, links[1] == `<https://docs.example.com/errors/out-of-credit>; rel="help"`)
+}
+
+func TestWriteJSON_WithInvalidDocHref(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDocHref("https://docs.example.com/>; rel=\"next\""),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrDocHrefFormat))
+}
+
+func TestWriteJSON_WithAllowedMethods(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithAllowedMethods(http.MethodGet, http.MethodPost),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, http.StatusMethodNotAllowed)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Allow") == "GET, POST")
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, http.StatusNotFound)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Allow") == "")
+}
+
+func TestWriteJSON_WithAllowedMethodsRedacted(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithAllowedMethods(http.MethodGet),
+	).Redact()
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, http.StatusMethodNotAllowed)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Allow") == "GET")
+}
+
+func TestWriteJSON_WithNoSniff(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithNoSniff(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("X-Content-Type-Options") == "nosniff")
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard)), 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("X-Content-Type-Options") == "")
+}
+
+func TestWriteJSON_WithNonce(t *testing.T) {
+	newData := func() *problemdetail.ProblemDetail {
+		return problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithNonce(),
+		)
+	}
+
+	data := newData()
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	nonce := rec.Header().Get("Nonce")
+	expectTrue(t, regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(nonce))
+	expectTrue(t, data.Extensions()["nonce"] == nonce)
+	expectTrue(t, newData().Extensions()["nonce"] != nonce)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(rec.Header().Get("Nonce")))
+	expectTrue(t, rec.Header().Get("Nonce") != nonce)
+	expectTrue(t, data.Extensions()["nonce"] == rec.Header().Get("Nonce"))
+}
+
+func TestWriteJSON_ReplacesContentType(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "text/html")
+	err := problemdetail.WriteJSON(rec, data, 500)
+	expectTrue(t, err == nil)
+	expectTrue(t, len(rec.Header().Values("Content-Type")) == 1)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+}
+
+func TestWriteJSON_WithScrubHeaders(t *testing.T) {
+	newHeader := func() http.Header {
+		h := make(http.Header)
+		h.Set("Retry-After", "120")
+		h.Set("ETag", `"stale"`)
+		h.Set("X-Request-Id", "abc")
+		h.Set("Vary", "Origin")
+		return h
+	}
+
+	for _, scrub := range []bool{false, true} {
+		opts := []problemdetail.Option{
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithNoSniff(),
+		}
+		if scrub {
+			opts = append(opts, problemdetail.WithScrubHeaders())
+		}
+		data := problemdetail.New(problemdetail.Untyped, opts...)
+
+		rec := httptest.NewRecorder()
+		for key, values := range newHeader() {
+			rec.Header()[key] = values
+		}
+		err := problemdetail.WriteJSON(rec, data, 500)
+		expectTrue(t, err == nil)
+		expectTrue(t, (rec.Header().Get("Retry-After") == "") == scrub)
+		expectTrue(t, (rec.Header().Get("ETag") == "") == scrub)
+		expectTrue(t, rec.Header().Get("X-Request-Id") == "abc")
+		expectTrue(t, rec.Header().Get("Vary") == "Origin")
+		expectTrue(t, rec.Header().Get("X-Content-Type-Options") == "nosniff")
+	}
+}
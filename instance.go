@@ -0,0 +1,118 @@
+package problemdetail
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// instanceBasePath is the path set by SetInstanceBasePath, nil means no base path.
+var instanceBasePath atomic.Pointer[string]
+
+// SetInstanceBasePath sets the base path that WithInstanceFromError prefixes the resource id with, for example with
+// "/orders" the resource id "42" becomes the instance "/orders/42". An empty path, the default, uses the resource id
+// as it is. It is safe for concurrent use, but it is meant to be set once at program start.
+func SetInstanceBasePath(base string) {
+	if base == "" {
+		instanceBasePath.Store(nil)
+		return
+	}
+	instanceBasePath.Store(&base)
+}
+
+// resourceIdentifier is implemented by the domain errors that carry the id of the resource they are about.
+type resourceIdentifier interface {
+	ResourceID() string
+}
+
+// WithInstanceFromError sets the instance of the ProblemDetail to the resource id carried by err, so the identity of
+// the resource flows from the domain error into the problem detail. An error carries a resource id if it, or any error
+// in its chain, implements:
+//
+//	interface{ ResourceID() string }
+//
+// The resource id is prefixed with the base path set by SetInstanceBasePath, if any. If err carries no resource id, or
+// an empty one, the option is a no-op.
+func WithInstanceFromError(err error) Option {
+	return func(pd *ProblemDetail) {
+		var r resourceIdentifier
+		if !errors.As(err, &r) {
+			return
+		}
+		id := r.ResourceID()
+		if id == "" {
+			return
+		}
+		if base := instanceBasePath.Load(); base != nil {
+			id = strings.TrimSuffix(*base, "/") + "/" + strings.TrimPrefix(id, "/")
+		}
+		WithInstance(id)(pd)
+	}
+}
+
+// WithResourceInstance sets the instance of the ProblemDetail to the path of a resource, /<kind>/<id>, where id is
+// formatted with fmt, for example WithResourceInstance("orders", 42) sets "/orders/42". Both segments are escaped, so
+// an id with a slash stays a single segment. An empty kind or id is reported as ErrInstanceFormat when the problem
+// detail is validated.
+func WithResourceInstance(kind string, id any) Option {
+	return func(pd *ProblemDetail) {
+		segment := fmt.Sprint(id)
+		if kind == "" || id == nil || segment == "" {
+			pd.errs = append(pd.errs, fmt.Errorf("%w: resource %q with id %q", ErrInstanceFormat, kind, segment))
+			return
+		}
+		WithInstance("/" + url.PathEscape(kind) + "/" + url.PathEscape(segment))(pd)
+	}
+}
+
+// WithInstanceHash sets the instance of the ProblemDetail to urn:hash:<hex>, where <hex> is the SHA-256 hash of the
+// given parts. So the same inputs, such as the route and the kind of failure, always give the same instance, and
+// clients can group the occurrences of a problem without the real resource path being exposed. The parts are joined
+// with a NUL byte, so ("ab", "c") and ("a", "bc") give different instances.
+func WithInstanceHash(parts ...string) Option {
+	return func(pd *ProblemDetail) {
+		sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+		WithInstance("urn:hash:" + hex.EncodeToString(sum[:]))(pd)
+	}
+}
+
+// instanceKey is the context key of the instance stored by ContextWithInstance.
+type instanceKey struct{}
+
+// ContextWithInstance returns a copy of ctx that carries the instance, typically the sanitized request URI stored by
+// a middleware, for WithInstanceFromContext.
+func ContextWithInstance(ctx context.Context, instance string) context.Context {
+	return context.WithValue(ctx, instanceKey{}, instance)
+}
+
+// InstanceFromContext returns the instance carried by ctx, it is used by WithInstanceFromContext. The default returns
+// the instance stored by ContextWithInstance. It can be replaced at program start to read the instance stored by an
+// existing middleware, for example:
+//
+//	problemdetail.InstanceFromContext = func(ctx context.Context) (string, bool) {
+//		uri, ok := ctx.Value(requestURIKey{}).(string)
+//		return uri, ok
+//	}
+var InstanceFromContext = func(ctx context.Context) (string, bool) {
+	instance, ok := ctx.Value(instanceKey{}).(string)
+	return instance, ok
+}
+
+// WithInstanceFromContext sets the instance of the ProblemDetail to the one carried by ctx, as returned by
+// InstanceFromContext. So the service layer, which only has the context and not the *http.Request, can still populate
+// the instance. If ctx carries no instance, or an empty one, the option is a no-op.
+func WithInstanceFromContext(ctx context.Context) Option {
+	return func(pd *ProblemDetail) {
+		if InstanceFromContext == nil {
+			return
+		}
+		if instance, ok := InstanceFromContext(ctx); ok && instance != "" {
+			WithInstance(instance)(pd)
+		}
+	}
+}
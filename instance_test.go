@@ -0,0 +1,95 @@
+package problemdetail_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+// OrderNotFoundError is a sample domain error that carries the id of the missing order.
+type OrderNotFoundError struct{ ID string }
+
+func (e *OrderNotFoundError) Error() string      { return "order not found: " + e.ID }
+func (e *OrderNotFoundError) ResourceID() string { return e.ID }
+
+func TestWithInstanceFromError(t *testing.T) {
+	err := fmt.Errorf("cancel order: %w", &OrderNotFoundError{ID: "42"})
+
+	pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceFromError(err))
+	expectTrue(t, pd.Instance == "42")
+
+	problemdetail.SetInstanceBasePath("/orders/")
+	t.Cleanup(func() { problemdetail.SetInstanceBasePath("") })
+
+	pd = problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceFromError(err))
+	expectTrue(t, pd.Instance == "/orders/42")
+
+	pd = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithInstance("/orders"),
+		problemdetail.WithInstanceFromError(errors.New("order not found")),
+	)
+	expectTrue(t, pd.Instance == "/orders")
+
+	pd = problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceFromError(nil))
+	expectTrue(t, pd.Instance == "")
+}
+
+func TestWithInstanceHash(t *testing.T) {
+	pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceHash("GET /orders/{id}", "not-found"))
+	expectTrue(t, strings.HasPrefix(pd.Instance, "urn:hash:"))
+	expectTrue(t, len(pd.Instance) == len("urn:hash:")+64)
+
+	same := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceHash("GET /orders/{id}", "not-found"))
+	expectTrue(t, pd.Instance == same.Instance)
+
+	a := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceHash("ab", "c"))
+	b := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceHash("a", "bc"))
+	expectTrue(t, a.Instance != b.Instance)
+
+	empty := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceHash())
+	expectTrue(t, empty.Instance == "urn:hash:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+}
+
+type requestURIKey struct{}
+
+func TestWithInstanceFromContext(t *testing.T) {
+	ctx := problemdetail.ContextWithInstance(context.Background(), "/orders/42")
+	pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceFromContext(ctx))
+	expectTrue(t, pd.Instance == "/orders/42")
+
+	pd = problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceFromContext(context.Background()))
+	expectTrue(t, pd.Instance == "")
+
+	original := problemdetail.InstanceFromContext
+	t.Cleanup(func() { problemdetail.InstanceFromContext = original })
+	problemdetail.InstanceFromContext = func(ctx context.Context) (string, bool) {
+		uri, ok := ctx.Value(requestURIKey{}).(string)
+		return uri, ok
+	}
+
+	ctx = context.WithValue(context.Background(), requestURIKey{}, "/carts/7")
+	pd = problemdetail.New(problemdetail.Untyped, problemdetail.WithInstanceFromContext(ctx))
+	expectTrue(t, pd.Instance == "/carts/7")
+}
+
+func TestWithResourceInstance(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithResourceInstance("orders", 42))
+	expectTrue(t, data.Instance == "/orders/42")
+
+	data = problemdetail.New(problemdetail.Untyped, problemdetail.WithResourceInstance("files", "a/b c"))
+	expectTrue(t, data.Instance == "/files/a%2Fb%20c")
+
+	for _, opt := range []problemdetail.Option{
+		problemdetail.WithResourceInstance("", 42),
+		problemdetail.WithResourceInstance("orders", nil),
+		problemdetail.WithResourceInstance("orders", ""),
+	} {
+		data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard), opt)
+		expectTrue(t, data.Instance == "")
+		expectTrue(t, errors.Is(data.Validate(), problemdetail.ErrInstanceFormat))
+	}
+}
@@ -0,0 +1,35 @@
+package problemdetail
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithDeprecation adds the deprecation extension member and makes the writers set the Deprecation header to the
+// HTTP-date at which the endpoint is, or will be, deprecated. So the lifecycle of a deprecated endpoint is signaled
+// alongside its problems. A zero time is a no-op.
+//
+// ref: https://datatracker.ietf.org/doc/html/draft-ietf-httpapi-deprecation-header
+func WithDeprecation(at time.Time) Option {
+	return withLifecycle("deprecation", "Deprecation", at)
+}
+
+// WithSunset adds the sunset extension member and makes the writers set the Sunset header to the HTTP-date at which
+// the endpoint is expected to become unresponsive. A zero time is a no-op.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc8594
+func WithSunset(at time.Time) Option {
+	return withLifecycle("sunset", "Sunset", at)
+}
+
+// withLifecycle adds the extension member name and the header key, both set to the time at.
+func withLifecycle(name, key string, at time.Time) Option {
+	return func(pd *ProblemDetail) {
+		if at.IsZero() {
+			return
+		}
+		WithExtension(name, at)(pd)
+		date := at.UTC().Format(http.TimeFormat)
+		pd.headers = append(pd.headers, func(h http.Header, _ *ProblemDetail) { h.Set(key, date) })
+	}
+}
@@ -0,0 +1,44 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSON_WithDeprecationAndSunset(t *testing.T) {
+	deprecation := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	sunset := time.Date(2024, 7, 1, 0, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDeprecation(deprecation),
+		problemdetail.WithSunset(sunset),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 410)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Deprecation") == "Tue, 02 Jan 2024 03:04:05 GMT")
+	expectTrue(t, rec.Header().Get("Sunset") == "Sun, 30 Jun 2024 17:00:00 GMT")
+
+	rawExp := `{"type":"about:blank","title":"Gone","status":410,"deprecation":"2024-01-02T03:04:05Z","sunset":"2024-07-01T00:00:00+07:00"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == rawExp)
+}
+
+func TestWriteJSON_WithZeroDeprecation(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDeprecation(time.Time{}),
+		problemdetail.WithSunset(time.Time{}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 410)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Deprecation") == "")
+	expectTrue(t, rec.Header().Get("Sunset") == "")
+	expectTrue(t, len(data.Extensions()) == 0)
+}
@@ -0,0 +1,141 @@
+package problemdetail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// HandlerFunc is an HTTP handler that returns its error instead of writing it, so the handler can simply return a
+// domain error, or a problem detail, and leave the response to Middleware:
+//
+//	mux.Handle("/orders", problemdetail.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		order, err := store.Order(r.URL.Query().Get("id"))
+//		if err != nil {
+//			return err
+//		}
+//		return json.NewEncoder(w).Encode(order)
+//	}))
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP implements http.Handler. A returned error is handed over to the enclosing Middleware, or written with
+// WriteError if the handler is not wrapped by one.
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := f(w, r)
+	if err == nil {
+		return
+	}
+	if slot, ok := r.Context().Value(errorSlotKey{}).(*error); ok {
+		*slot = err
+		return
+	}
+	_ = WriteError(w, r, err)
+}
+
+// errorSlotKey is the context key of the slot that HandlerFunc stores its error in for Middleware.
+type errorSlotKey struct{}
+
+// MiddlewareOption customizes Middleware.
+type MiddlewareOption func(*middleware)
+
+// middleware is the configuration of Middleware.
+type middleware struct {
+	recover bool
+	mapper  func(err error) ProblemDetailer
+	logger  *slog.Logger
+}
+
+// WithRecover makes Middleware recover the panics of the handlers and write them as a 500 (Internal Server Error)
+// problem detail, without the panic value, so it is not leaked to the client. As net/http does, http.ErrAbortHandler
+// is not recovered.
+func WithRecover() MiddlewareOption {
+	return func(m *middleware) { m.recover = true }
+}
+
+// WithErrorMapper sets the function that converts the errors of the handlers into problem details, the status code of
+// the problem detail is written, or 500 (Internal Server Error) if it has none. The default converts them as
+// WriteError does. A mapper that returns nil falls back to the default.
+func WithErrorMapper(fn func(err error) ProblemDetailer) MiddlewareOption {
+	return func(m *middleware) { m.mapper = fn }
+}
+
+// WithLogger makes Middleware log every error of the handlers, at the error level, with the members of the problem
+// detail written for it. The error of writing the problem detail, if any, is logged too.
+func WithLogger(logger *slog.Logger) MiddlewareOption {
+	return func(m *middleware) { m.logger = logger }
+}
+
+// Middleware returns a middleware that turns the errors returned by the HandlerFunc handlers it wraps into problem
+// details, written in the format accepted by the request as Write does. The handlers that are plain http.Handler are
+// served as they are, so Middleware can wrap a whole mux. If a handler already wrote the status code before returning
+// its error, the response cannot be replaced anymore, the error is only logged. The handlers can tell whether the
+// response is committed with AlreadyWritten. The response writer given to the handlers implements http.Flusher and
+// http.Hijacker, which are forwarded to the underlying response writer, and supports http.ResponseController.
+func Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := new(middleware)
+	for _, opt := range opts {
+		opt(m)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var err error
+			r = r.WithContext(context.WithValue(r.Context(), errorSlotKey{}, &err))
+			rec := &statusRecorder{ResponseWriter: w}
+			if m.recover {
+				defer m.recoverPanic(rec, r)
+			}
+			next.ServeHTTP(rec, r)
+			if err != nil {
+				m.handle(rec, r, err)
+			}
+		})
+	}
+}
+
+// recoverPanic writes the panic of a handler, if any, as a problem detail.
+func (m *middleware) recoverPanic(w http.ResponseWriter, r *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	m.handle(w, r, fmt.Errorf("panic: %v", v))
+}
+
+// handle converts err into a problem detail and writes it. If the response is already committed, Write fails with
+// ErrAlreadyWritten, which is logged.
+func (m *middleware) handle(w http.ResponseWriter, r *http.Request, err error) {
+	var pd ProblemDetailer
+	if m.mapper != nil {
+		pd = m.mapper(err)
+	}
+	var status int
+	if pd == nil {
+		pd, status = problemFromError(err)
+	} else {
+		status = statusOf(pd)
+	}
+
+	m.log(r, pd, err, Write(w, r, pd, status))
+}
+
+// log logs the error of a handler with the members of its problem detail, if a logger is set.
+func (m *middleware) log(r *http.Request, pd ProblemDetailer, err, writeErr error) {
+	if m.logger == nil {
+		return
+	}
+	args := []any{"error", err.Error()}
+	if p := baseOf(pd); p != nil {
+		fields := p.Fields()
+		for _, name := range sortedKeys(fields) {
+			args = append(args, name, fields[name])
+		}
+	}
+	if writeErr != nil {
+		args = append(args, "writeError", writeErr.Error())
+	}
+	m.logger.ErrorContext(r.Context(), "problem", args...)
+}
@@ -0,0 +1,112 @@
+package problemdetail_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+var errOrderNotFound = errors.New("order not found")
+
+func TestMiddleware(t *testing.T) {
+	problemdetail.RegisterError(errOrderNotFound, 404)
+
+	var logs bytes.Buffer
+	mw := problemdetail.Middleware(problemdetail.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	handler := mw(problemdetail.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("get order 42: %w", errOrderNotFound)
+	}))
+
+	req := httptest.NewRequest("GET", "/orders/42", nil)
+	req.Header.Set("Accept", "application/xml")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	expectTrue(t, rec.Code == 404)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+xml; charset=utf-8")
+	expectTrue(t, strings.Contains(rec.Body.String(), "<detail>get order 42: order not found</detail>"))
+	expectTrue(t, strings.Contains(logs.String(), `msg=problem error="get order 42: order not found"`))
+	expectTrue(t, strings.Contains(logs.String(), "status=404"))
+
+	ok := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(204) }))
+	rec = httptest.NewRecorder()
+	ok.ServeHTTP(rec, req)
+	expectTrue(t, rec.Code == 204)
+}
+
+func TestMiddleware_WithErrorMapper(t *testing.T) {
+	mw := problemdetail.Middleware(problemdetail.WithErrorMapper(func(err error) problemdetail.ProblemDetailer {
+		if errors.Is(err, errOrderNotFound) {
+			pd := problemdetail.New("https://example.com/probs/order-not-found",
+				problemdetail.WithValidateLevel(problemdetail.LStandard),
+				problemdetail.WithTitle("The order does not exist."),
+			)
+			pd.WriteStatus(410)
+			return pd
+		}
+		return nil
+	}))
+
+	for _, tt := range []struct {
+		err  error
+		code int
+	}{
+		{err: errOrderNotFound, code: 410},
+		{err: errors.New("boom"), code: 500},
+	} {
+		handler := mw(problemdetail.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error { return tt.err }))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		expectTrue(t, rec.Code == tt.code)
+		expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+	}
+}
+
+func TestMiddleware_WithRecover(t *testing.T) {
+	handler := problemdetail.Middleware(problemdetail.WithRecover())(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) { panic("secret database password") },
+	))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expectTrue(t, rec.Code == 500)
+	expectTrue(t, !strings.Contains(rec.Body.String(), "secret"))
+
+	abort := problemdetail.Middleware(problemdetail.WithRecover())(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) },
+	))
+	defer func() { expectTrue(t, recover() == http.ErrAbortHandler) }()
+	abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestMiddleware_AlreadyWritten(t *testing.T) {
+	var logs bytes.Buffer
+	mw := problemdetail.Middleware(problemdetail.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	handler := mw(problemdetail.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		_, _ = w.Write([]byte(`{"items":[`))
+		return errors.New("stream broken")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expectTrue(t, rec.Code == 200)
+	expectTrue(t, rec.Body.String() == `{"items":[`)
+	expectTrue(t, strings.Contains(logs.String(), "response is already written"))
+}
+
+func TestHandlerFunc_WithoutMiddleware(t *testing.T) {
+	handler := problemdetail.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expectTrue(t, rec.Code == 500)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+}
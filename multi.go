@@ -0,0 +1,52 @@
+package problemdetail
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// WriteJSONMulti writes the problem details to the response writer as a JSON array, with the given status code, for
+// example 207 (Multi-Status) for a batch endpoint that reports the outcome of every item. The content type is set to
+// application/problem+json; charset=utf-8, and the headers set by the options of every problem detail are written.
+//
+// Unlike WriteJSON, the status code is only written to the status line, the ProblemDetail.Status of every element is
+// kept as it is, since it describes the outcome of its own item.
+//
+// Every element is validated and encoded before the response is committed, so if one of them is invalid, an error is
+// returned and the response is left unchanged. Like WriteJSON, it returns ErrAlreadyWritten if the response is already
+// committed, see AlreadyWritten.
+func WriteJSONMulti(w http.ResponseWriter, pds []ProblemDetailer, status int) error {
+	if AlreadyWritten(w) {
+		return fmt.Errorf("WriteJSONMulti: %w", stageError(ErrIO, ErrAlreadyWritten))
+	}
+
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, pd := range pds {
+		raw, err := encode(pd, marshalJSON)
+		if err != nil {
+			return fmt.Errorf("WriteJSONMulti: element %d: %w", i, err)
+		}
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(raw)
+	}
+	body.WriteByte(']')
+	if !omitNewline.Load() {
+		body.WriteByte('\n')
+	}
+
+	for _, pd := range pds {
+		writeHeaders(w.Header(), pd, "")
+	}
+	writeContentTypeAndStatus(w, formatJSON.contentType, status)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		return fmt.Errorf("WriteJSONMulti: %w", stageError(ErrIO, err))
+	}
+	if err := flush(w); err != nil {
+		return fmt.Errorf("WriteJSONMulti: %w", stageError(ErrIO, err))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSONMulti(t *testing.T) {
+	notFound := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithInstance("/products/42"),
+	)
+	notFound.WriteStatus(404)
+	outOfCredit := problemdetail.Errorf("https://example.com/probs/out-of-credit", 403, "balance is %d", 30)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSONMulti(rec, []problemdetail.ProblemDetailer{notFound, outOfCredit}, 207)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Code == 207)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+
+	rawExp := `[{"type":"about:blank","title":"Not Found","status":404,"instance":"/products/42"},` +
+		`{"type":"https://example.com/probs/out-of-credit","title":"Forbidden","status":403,"detail":"balance is 30"}]`
+	expectTrue(t, rec.Body.String() == rawExp+"\n")
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSONMulti(rec, nil, 207)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Body.String() == "[]\n")
+}
+
+func TestWriteJSONMulti_WithInvalidElement(t *testing.T) {
+	valid := problemdetail.Errorf(problemdetail.Untyped, 404, "product 42 not found")
+	invalid := problemdetail.New("", problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	rec := httptest.NewRecorder()
+	rec.Code = 0
+	err := problemdetail.WriteJSONMulti(rec, []problemdetail.ProblemDetailer{valid, invalid}, 207)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, rec.Code == 0)
+	expectTrue(t, rec.Body.Len() == 0)
+	expectTrue(t, rec.Header().Get("Content-Type") == "")
+}
+
+func TestWriteJSONMulti_AlreadyWritten(t *testing.T) {
+	var writeErr error
+	handler := problemdetail.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		pd := problemdetail.Errorf(problemdetail.Untyped, 404, "product 42 not found")
+		writeErr = problemdetail.WriteJSONMulti(w, []problemdetail.ProblemDetailer{pd}, 207)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expectTrue(t, errors.Is(writeErr, problemdetail.ErrAlreadyWritten))
+	expectTrue(t, errors.Is(writeErr, problemdetail.ErrIO))
+	expectTrue(t, rec.Code == 200)
+	expectTrue(t, rec.Body.String() == "partial")
+}
@@ -0,0 +1,168 @@
+package problemdetail
+
+import (
+	"maps"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// translation is a localized title and detail added by WithTranslation.
+type translation struct {
+	tag    string
+	title  string
+	detail string
+}
+
+// WithTranslation adds the title and detail of the ProblemDetail in the language of the given tag, such as "id" or
+// "pt-BR". Write chooses the translation from the Accept-Language header of the request. An empty title or detail
+// keeps the original one.
+func WithTranslation(tag, title, detail string) Option {
+	return func(pd *ProblemDetail) {
+		if pd.translations == nil {
+			pd.translations = make(map[string]translation)
+		}
+		pd.translations[strings.ToLower(tag)] = translation{tag: tag, title: title, detail: detail}
+	}
+}
+
+// WithDetailTranslations adds the detailTranslations extension member, an object of the detail in every language,
+// keyed by language tag, such as {"en":"...","id":"..."}. So a client that works offline can pick the best match
+// itself, while ProblemDetail.Detail stays in the negotiated or default language. The map is copied, an empty map is a
+// no-op.
+func WithDetailTranslations(details map[string]string) Option {
+	return func(pd *ProblemDetail) {
+		if len(details) == 0 {
+			return
+		}
+		WithExtension("detailTranslations", maps.Clone(details))(pd)
+	}
+}
+
+// Write writes the problem detail in the format accepted by the request, according to its Accept header. It writes
+// XML, the same as WriteXML, if the request prefers application/problem+xml, application/xml or text/xml, and JSON,
+// the same as WriteJSON, otherwise. Accept is added to the Vary header, so a cache does not serve the JSON response to
+// an XML client.
+//
+// If the problem detail has translations, the title and detail are localized according to the Accept-Language header
+// of the request. The Content-Language header is set to the language of the chosen translation, and Accept-Language is
+// added to the Vary header.
+//
+// If the problem detail has WithETag and the request has a matching If-None-Match header, 304 (Not Modified) is
+// written instead of the problem detail.
+//
+// If the problem detail is invalid, an error is returned.
+func Write(w http.ResponseWriter, r *http.Request, pd ProblemDetailer, code int) error {
+	return defaultWriter.Negotiate(w, r, pd, code)
+}
+
+// translate applies the translation of the given key, if any. It returns a function that restores the members it
+// changed to their original values, so the translation only applies to the write that negotiated it.
+func (p *ProblemDetail) translate(language string) (restore func()) {
+	t, ok := p.translations[language]
+	if language == "" || !ok {
+		return func() {}
+	}
+	title, detail := p.Title, p.Detail
+	if t.title != "" {
+		p.Title = t.title
+	}
+	if t.detail != "" {
+		p.Detail = t.detail
+	}
+	return func() {
+		if t.title != "" {
+			p.Title = title
+		}
+		if t.detail != "" {
+			p.Detail = detail
+		}
+	}
+}
+
+// negotiateLanguage returns the key of the translation that best matches the Accept-Language header. A language range
+// matches a translation either exactly or after removing its trailing subtags, such as "en-US" matches "en".
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc4647#section-3.4
+func (p *ProblemDetail) negotiateLanguage(header []string) (string, bool) {
+	for _, lang := range parseQuality(header) {
+		for tag := strings.ToLower(lang); tag != ""; {
+			if _, ok := p.translations[tag]; ok {
+				return tag, true
+			}
+			i := strings.LastIndexByte(tag, '-')
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return "", false
+}
+
+// Set of media types that are written as XML by Write, any other media type is written as JSON.
+var xmlMediaTypes = map[string]struct{}{
+	"application/problem+xml": {},
+	"application/xml":         {},
+	"text/xml":                {},
+}
+
+// negotiateFormat returns the format of the most preferred media type of the Accept header. The fallback is returned
+// if the header accepts any media type, or none of the media types it accepts is JSON or XML.
+func negotiateFormat(header []string, fallback format) format {
+	for _, mediaType := range parseQuality(header) {
+		if _, ok := xmlMediaTypes[mediaType]; ok {
+			return formatXML
+		}
+		if mediaType == "application/problem+json" || mediaType == "application/json" {
+			return formatJSON
+		}
+		if mediaType == "application/*" || mediaType == "*/*" {
+			return fallback
+		}
+	}
+	return fallback
+}
+
+// parseQuality parses a header of comma-separated values with an optional quality weight, such as the Accept and the
+// Accept-Language headers. It returns the values with a non-zero weight, the most preferred one first.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9110#section-12.4.2
+func parseQuality(header []string) []string {
+	type weighted struct {
+		value string
+		q     float64
+	}
+
+	var values []weighted
+	for _, line := range header {
+		for _, item := range strings.Split(line, ",") {
+			value, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+			if err != nil && value == "" {
+				value, _, _ = strings.Cut(strings.TrimSpace(item), ";")
+				value = strings.ToLower(strings.TrimSpace(value))
+			}
+			if value == "" {
+				continue
+			}
+			q := 1.0
+			if raw, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(raw, 64); err != nil {
+					continue
+				}
+			}
+			if q > 0 {
+				values = append(values, weighted{value: value, q: q})
+			}
+		}
+	}
+
+	sort.SliceStable(values, func(i, j int) bool { return values[i].q > values[j].q })
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = v.value
+	}
+	return result
+}
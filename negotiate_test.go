@@ -0,0 +1,167 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWrite_NegotiatesFormat(t *testing.T) {
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{accept: "", contentType: "application/problem+json; charset=utf-8"},
+		{accept: "*/*", contentType: "application/problem+json; charset=utf-8"},
+		{accept: "application/problem+xml", contentType: "application/problem+xml; charset=utf-8"},
+		{accept: "application/json;q=0.5, application/xml", contentType: "application/problem+xml; charset=utf-8"},
+		{accept: "text/xml;q=0.5, application/problem+json", contentType: "application/problem+json; charset=utf-8"},
+		{accept: "text/html", contentType: "application/problem+json; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.accept)
+
+		rec := httptest.NewRecorder()
+		err := problemdetail.Write(rec, req, data, 404)
+		expectTrue(t, err == nil)
+		expectTrue(t, rec.Code == 404)
+		expectTrue(t, rec.Header().Get("Content-Type") == tt.contentType)
+	}
+}
+
+func TestWrite_WithTranslation(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+		problemdetail.WithTranslation("id", "Kredit Anda tidak cukup.", "Saldo Anda 30, tetapi biayanya 50."),
+		problemdetail.WithTranslation("pt-BR", "Você não tem crédito suficiente.", ""),
+	)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fr;q=0.9, id-ID, en;q=0.5")
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.Write(rec, req, data, 403)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"https://example.com/probs/out-of-credit","title":"Kredit Anda tidak cukup.","status":403,"detail":"Saldo Anda 30, tetapi biayanya 50."}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+	expectTrue(t, rec.Header().Get("Content-Language") == "id")
+	expectTrue(t, strings.Join(rec.Header().Values("Vary"), ", ") == "Accept, Accept-Language")
+
+	expectTrue(t, data.Title == "You do not have enough credit.")
+	expectTrue(t, data.Detail == "Your current balance is 30, but that costs 50.")
+
+	req.Header.Set("Accept-Language", "pt-br")
+	rec = httptest.NewRecorder()
+	err = problemdetail.Write(rec, req, data, 403)
+	expectTrue(t, err == nil)
+	expRaw = `{"type":"https://example.com/probs/out-of-credit","title":"Você não tem crédito suficiente.","status":403,"detail":"Your current balance is 30, but that costs 50."}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+	expectTrue(t, rec.Header().Get("Content-Language") == "pt-BR")
+
+	req.Header.Set("Accept-Language", "en")
+	rec = httptest.NewRecorder()
+	err = problemdetail.Write(rec, req, data, 403)
+	expectTrue(t, err == nil)
+	expRaw = `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"detail":"Your current balance is 30, but that costs 50."}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+	expectTrue(t, rec.Header().Get("Content-Language") == "")
+}
+
+func TestWrite_WithTranslationUntyped(t *testing.T) {
+	newData := func() *problemdetail.ProblemDetail {
+		return problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+			problemdetail.WithTranslation("id", "Dilarang", ""),
+		)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "id")
+
+	translated := newData()
+	rec := httptest.NewRecorder()
+	err := problemdetail.Write(rec, req, translated, 403)
+	expectTrue(t, err == nil)
+	expRaw := `{"type":"about:blank","title":"Dilarang","status":403,"detail":"Your current balance is 30, but that costs 50."}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	// a translated write leaves the problem detail as a write without translation does.
+	plain := newData()
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), plain, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, translated.Title == "Forbidden")
+	expectTrue(t, translated.Equal(plain))
+}
+
+func TestWrite_WithTranslationNoMatch(t *testing.T) {
+	data := problemdetail.New(
+		"https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithTranslation("id", "Kredit Anda tidak cukup.", ""),
+	)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fr, id;q=0")
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.Write(rec, req, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Title == "You do not have enough credit.")
+	expectTrue(t, rec.Header().Get("Content-Language") == "")
+	expectTrue(t, strings.Join(rec.Header().Values("Vary"), ", ") == "Accept, Accept-Language")
+}
+
+func TestWrite_Vary(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/problem+xml")
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Vary", "Origin, accept")
+	err := problemdetail.Write(rec, req, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Values("Vary")[0] == "Origin, accept")
+	expectTrue(t, len(rec.Header().Values("Vary")) == 1)
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set("Vary", "Origin")
+	err = problemdetail.Write(rec, req, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.Join(rec.Header().Values("Vary"), ", ") == "Origin, Accept")
+}
+
+func TestWriteJSON_WithDetailTranslations(t *testing.T) {
+	details := map[string]string{
+		"en": "Your current balance is 30, but that costs 50.",
+		"id": "Saldo Anda 30, tetapi biayanya 50.",
+	}
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail(details["en"]),
+		problemdetail.WithDetailTranslations(details),
+	)
+	details["fr"] = "Votre solde est de 30, mais cela coûte 50."
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"about:blank","title":"Forbidden","status":403,"detail":"Your current balance is 30, but that costs 50.",` +
+		`"detailTranslations":{"en":"Your current balance is 30, but that costs 50.","id":"Saldo Anda 30, tetapi biayanya 50."}}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	data = problemdetail.New(problemdetail.Untyped, problemdetail.WithDetailTranslations(nil))
+	expectTrue(t, len(data.Extensions()) == 0)
+}
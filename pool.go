@@ -0,0 +1,42 @@
+package problemdetail
+
+import "sync"
+
+// pool is the pool of problem details used by Acquire and Release.
+var pool = sync.Pool{
+	New: func() any { return new(ProblemDetail) },
+}
+
+// Acquire returns a problem detail from the pool, in the same state as New("") returns: all members are empty, the
+// validation level is LStrict and the options set by SetDefaultOptions are applied. It is meant for error paths with a
+// very high rate, to reduce allocations.
+//
+// The caller owns the returned problem detail until it calls ProblemDetail.Release.
+func Acquire() *ProblemDetail {
+	pd := pool.Get().(*ProblemDetail)
+	pd.flags = LStrict
+	pd.applyDefaultOptions()
+	return pd
+}
+
+// Release resets the problem detail and returns it to the pool used by Acquire.
+//
+// Ownership: after Release, the caller must not use the problem detail, nor any value that was set on it, such as an
+// extension member, since it may be handed out again by Acquire at any time. The writers fully encode the response
+// before returning, so it is safe to release the problem detail right after a writer returns.
+func (p *ProblemDetail) Release() {
+	p.Reset()
+	pool.Put(p)
+}
+
+// Reset zeroes all members of the problem detail, including the extension members and the settings of the options,
+// and sets the validation level back to LStrict; the options set by SetDefaultOptions are not applied. The extension
+// map is cleared rather than reallocated, to reuse its capacity, for example in a custom sync.Pool.
+//
+// Ownership: after Reset, the caller must not hold references to the values that were set before, such as an
+// extension member, since the reused problem detail no longer owns them.
+func (p *ProblemDetail) Reset() {
+	extensions := p.extensions
+	clear(extensions)
+	*p = ProblemDetail{flags: LStrict, extensions: extensions}
+}
@@ -0,0 +1,74 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestAcquire(t *testing.T) {
+	pd := problemdetail.Acquire()
+	pd.Type = problemdetail.Untyped
+	problemdetail.WithExtension("balance", 30)(pd)
+	pd.Release()
+
+	pd = problemdetail.Acquire()
+	defer pd.Release()
+
+	expectTrue(t, pd.Type == "")
+	expectTrue(t, len(pd.Extensions()) == 0)
+
+	pd.Type = problemdetail.Untyped
+	err := pd.Validate()
+	expectTrue(t, err != nil) // the validation level is back to LStrict.
+
+	problemdetail.WithValidateLevel(problemdetail.LStandard)(pd)
+	rec := httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, pd, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Forbidden","status":403}`)
+}
+
+func TestProblemDetail_Reset(t *testing.T) {
+	pd := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithDedupKey("out-of-credit-12345"),
+	)
+	pd.WriteStatus(403)
+
+	pd.Reset()
+	expectTrue(t, pd.Equal(problemdetail.New("")))
+	expectTrue(t, len(pd.Extensions()) == 0)
+
+	pd.Type = problemdetail.Untyped
+	expectTrue(t, pd.Validate() != nil) // the validation level is back to LStrict.
+
+	problemdetail.WithValidateLevel(problemdetail.LStandard)(pd)
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, pd, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("X-Dedup-Key") == "")
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Forbidden","status":403}`)
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pd := problemdetail.New(problemdetail.Untyped)
+		pd.WriteStatus(503)
+	}
+}
+
+func BenchmarkAcquire(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pd := problemdetail.Acquire()
+		pd.Type = problemdetail.Untyped
+		pd.WriteStatus(503)
+		pd.Release()
+	}
+}
@@ -1,12 +1,18 @@
 package problemdetail
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Error is an error type for ProblemDetail.
@@ -17,15 +23,51 @@ func (e Error) Error() string { return string(e) }
 
 // Set of errors for ProblemDetail.
 const (
-	ErrTypeRequired     = Error("type is required")
-	ErrTitleRequired    = Error("title is required")
-	ErrStatusRequired   = Error("status is required")
-	ErrDetailRequired   = Error("detail is required")
-	ErrInstanceRequired = Error("instance is required")
-	ErrTypeFormat       = Error("type is not a valid URI")
-	ErrInstanceFormat   = Error("instance is not a valid URI")
+	ErrTypeRequired             = Error("type is required")
+	ErrTitleRequired            = Error("title is required")
+	ErrStatusRequired           = Error("status is required")
+	ErrDetailRequired           = Error("detail is required")
+	ErrInstanceRequired         = Error("instance is required")
+	ErrTypeFormat               = Error("type is not a valid URI")
+	ErrInstanceFormat           = Error("instance is not a valid URI")
+	ErrTemplateKey              = Error("detail template refers to a missing extension")
+	ErrReservedExtension        = Error("extension name is reserved for a standard member")
+	ErrExtensionStruct          = Error("extension struct is neither a struct nor a map")
+	ErrExtensionNotSerializable = Error("extension cannot be encoded as JSON")
+	ErrPointerFormat            = Error("pointer is not a valid JSON Pointer")
+	ErrSeverityFormat           = Error("severity is not one of info, warning, error or critical")
+	ErrControlChars             = Error("member contains a control character")
+	ErrDocHrefFormat            = Error("documentation link is not a valid URL")
+	ErrDuplicateOption          = Error("option is applied more than once")
+	ErrTypeInstanceSame         = Error("type and instance are the same")
+	ErrCodeFormat               = Error("code does not match the code pattern")
+	ErrParseTarget              = Error("parse target is not a struct embedding *ProblemDetail")
+	ErrTooManyExtensions        = Error("problem detail has too many extensions")
+	ErrExtensionSchema          = Error("extensions do not conform to the registered schema")
+	ErrAlreadyWritten           = Error("response is already written")
+	ErrXMLName                  = Error("name is not a valid XML element name")
 )
 
+// Set of stages of the writers, an error returned by a writer wraps the stage that failed and the underlying cause, so
+// a validation failure can be told from an IO failure with errors.Is, for example:
+//
+//	if errors.Is(err, problemdetail.ErrIO) {
+//		// the client went away, the problem detail itself is fine.
+//	}
+const (
+	ErrValidation = Error("validate")
+	ErrMarshal    = Error("marshal")
+	ErrIO         = Error("io")
+)
+
+// stageError wraps err with the stage that failed, it returns nil if err is nil.
+func stageError(stage Error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", stage, err)
+}
+
 // ProblemDetail is a problem detail as defined in RFC 7807.
 // ref: https://tools.ietf.org/html/rfc7807
 type ProblemDetail struct {
@@ -64,7 +106,86 @@ type ProblemDetail struct {
 	Instance string `json:"instance,omitempty" xml:"instance,omitempty"`
 
 	// flags is the level of validation to perform on the ProblemDetail.
-	flags validationLevel
+	flags ValidateLevel
+
+	// detailOn5xx requires ProblemDetail.Detail for server errors, regardless of flags.
+	detailOn5xx bool
+
+	// suppressStatus omits ProblemDetail.Status from the body, it is still written to the status line.
+	suppressStatus bool
+
+	// statusAsString writes ProblemDetail.Status to the JSON body as a string.
+	statusAsString bool
+
+	// noStatus marks ProblemDetail.Status as intentionally absent, it is neither validated nor written to the body.
+	noStatus bool
+
+	// extensions is the set of extension members added by options, they are written next to the standard members.
+	//
+	// ref: https://tools.ietf.org/html/rfc7807#section-3.2
+	extensions map[string]any
+
+	// headers are applied to the response header by the writers, right before the status code is written. They are
+	// given the problem detail being written, which may be a copy of the one they were added to, such as one returned
+	// by Redact, so they must not capture the problem detail.
+	headers []func(h http.Header, p *ProblemDetail)
+
+	// xml customizes the XML encoding used by WriteXML.
+	xml xmlOptions
+
+	// titleFromStatus sets ProblemDetail.Title from the status code at write time, see WithTitleFromStatus.
+	titleFromStatus bool
+
+	// titleFromDetail derives ProblemDetail.Title from the detail at write time, see WithTitleFromDetail.
+	titleFromDetail bool
+
+	// defaults fills the empty members at write time, see WithDefaults.
+	defaults bool
+
+	// etag makes the writers set the ETag header, see WithETag.
+	etag bool
+
+	// nonce makes the writers set a new nonce extension member on every write, see WithNonce.
+	nonce bool
+
+	// detailMaxBytes is the maximum size of ProblemDetail.Detail in bytes, 0 means unlimited.
+	detailMaxBytes int
+
+	// detailTemplate is rendered against extensions at write time to produce ProblemDetail.Detail.
+	detailTemplate string
+
+	// retryAfter is the Retry-After header of the response the problem detail is read from.
+	retryAfter string
+
+	// translations are the localized titles and details, keyed by lower-cased language tag.
+	translations map[string]translation
+
+	// errs are the errors recorded while applying the options, since an Option cannot return an error.
+	errs []error
+
+	// applied are the names of the standard members set by the options, in the order the options are applied.
+	applied []string
+
+	// keyTransform transforms the names of the extension members in the JSON encoding, nil means they are kept.
+	keyTransform func(string) string
+
+	// envelope is the name of the member of the wrapper object the JSON encoding is nested under, empty means none.
+	envelope string
+
+	// strictOptions reports a standard member that is set by more than one option as ErrDuplicateOption.
+	strictOptions bool
+
+	// causes are the errors attached by WithCauses, they are returned by Unwrap.
+	causes []error
+
+	// scrubHeaders deletes the managed headers before the writers set them, see WithScrubHeaders.
+	scrubHeaders bool
+
+	// typeBase is the base URL set by WithContextDefaults, nil means the one set by SetTypeBaseURL.
+	typeBase *url.URL
+
+	// request is the request echoed by WithRequest, see requestEcho.
+	request requestEcho
 }
 
 // ProblemDetailer is contract for ProblemDetail, this interface is to make ProblemDetail extension possible by using
@@ -85,53 +206,291 @@ type ProblemDetailer interface {
 	WriteStatus(code int)
 }
 
+// Validator is implemented by a type that embeds ProblemDetail to validate its own fields, for example that a balance
+// is not negative. The writers call ValidateProblem after ProblemDetailer.Validate, and both errors are joined.
+type Validator interface {
+	ValidateProblem() error
+}
+
+// validate validates the problem detail, the fields of the type that embeds it, if any, and its own fields if it
+// implements Validator.
+func validate(pd ProblemDetailer) error {
+	errs := []error{pd.Validate(), validateEmbeddingFields(pd), validateExtensionSchema(pd)}
+	if v, ok := pd.(Validator); ok {
+		errs = append(errs, v.ValidateProblem())
+	}
+	return errors.Join(errs...)
+}
+
 // Option is the type for customizing the ProblemDetail.
 type Option func(*ProblemDetail)
 
 // Untyped is the default value for ProblemDetail.Type.
-// When using this value, ProblemDetail.Title will be set to http.StatusText(code).
+// When using this value, ProblemDetail.Title will be set to http.StatusText(code), unless SetStatusTitleFunc is used.
 const Untyped = "about:blank"
 
-// New creates a new ProblemDetail with the given type and options.
+// New creates a new ProblemDetail with the given type and options. If typ is a code registered by RegisterTypeAlias,
+// it is expanded to its type URI. The options set by SetDefaultOptions are applied before the given options.
 func New(typ string, opts ...Option) *ProblemDetail {
-	pd := ProblemDetail{
-		Type:  typ,
-		flags: LStrict,
-	}
+	pd := newPlain(resolveType(typ))
+	pd.applyDefaultOptions()
 	for _, opt := range opts {
-		opt(&pd)
+		opt(pd)
 	}
-	return &pd
+	return pd
+}
+
+// newPlain is like New without options, nor the options set by SetDefaultOptions, for the problem details that are
+// decoded, whose members come from the encoding only.
+func newPlain(typ string) *ProblemDetail {
+	return &ProblemDetail{Type: typ, flags: LStrict}
 }
 
+// NewStrict is like New, but the problem detail is validated right away, so a misconfiguration is reported close to
+// its source instead of at write time. Since ProblemDetail.Status is only known at write time, it is not validated,
+// nor are the members that the writers fill, such as the title of an untyped problem or a templated detail.
+func NewStrict(typ string, opts ...Option) (*ProblemDetail, error) {
+	pd := New(typ, opts...)
+	if err := pd.validateConstruction(); err != nil {
+		return nil, fmt.Errorf("NewStrict: %w", err)
+	}
+	return pd, nil
+}
+
+// MustNew is like NewStrict, but it panics if the problem detail is invalid. It simplifies the initialization of
+// package-level problem details, such as a catalog of problem templates, so an invalid type URI fails loudly at program
+// start, the same as regexp.MustCompile. It should not be used for problem details built at request time.
+func MustNew(typ string, opts ...Option) *ProblemDetail {
+	pd := New(typ, opts...)
+	if err := pd.validateConstruction(); err != nil {
+		panic(fmt.Sprintf("problemdetail: MustNew(%q): %v", typ, err))
+	}
+	return pd
+}
+
+// Errorf creates a problem detail to be returned as an error, with the given status, the status text as title and the
+// formatted detail. For example:
+//
+//	return problemdetail.Errorf(TypOutOfCredit, http.StatusForbidden, "balance is %d, but that costs %d", 30, 50)
+//
+// Since the instance is rarely known where the error is created, the validation level is LStandard.
+func Errorf(typ string, status int, format string, args ...any) *ProblemDetail {
+	pd := New(typ,
+		WithValidateLevel(LStandard),
+		WithTitle(statusTitle(status)),
+		WithDetail(fmt.Sprintf(format, args...)),
+	)
+	pd.WriteStatus(status)
+	return pd
+}
+
+// Problem is a short alias of ProblemDetail, for terser handler code.
+type Problem = ProblemDetail
+
+// P is a short alias of New, for terser handler code.
+func P(typ string, opts ...Option) *Problem { return New(typ, opts...) }
+
 // Kind returns the ProblemDetail.Type.
 func (p *ProblemDetail) Kind() string { return p.Type }
 
+// StatusCode returns the ProblemDetail.Status, 0 if it is not set yet.
+func (p *ProblemDetail) StatusCode() int { return p.Status }
+
+// Is4xx returns true if ProblemDetail.Status is a client error, from 400 to 499. It returns false if the status is not
+// set.
+func (p *ProblemDetail) Is4xx() bool { return p.Status >= 400 && p.Status < 500 }
+
+// Is5xx returns true if ProblemDetail.Status is a server error, from 500 to 599. It returns false if the status is not
+// set.
+func (p *ProblemDetail) Is5xx() bool { return p.Status >= 500 && p.Status < 600 }
+
+// IsUntyped returns true if ProblemDetail.Type is empty or Untyped, which both mean "about:blank".
+func (p *ProblemDetail) IsUntyped() bool { return p.Type == "" || p.Type == Untyped }
+
+// base returns the ProblemDetail itself. Since it is promoted by struct embedding, the writers can reach the embedded
+// ProblemDetail of an extension type.
+func (p *ProblemDetail) base() *ProblemDetail { return p }
+
+// baser is implemented by ProblemDetail and every type that embeds it.
+type baser interface{ base() *ProblemDetail }
+
+// baseOf returns the ProblemDetail behind pd, or nil if pd does not embed one.
+func baseOf(pd ProblemDetailer) *ProblemDetail {
+	b, ok := pd.(baser)
+	if !ok {
+		return nil
+	}
+	return b.base()
+}
+
 // Error implements error interface.
 func (p *ProblemDetail) Error() string { return fmt.Sprintf("problem detail: %s", p.Type) }
 
+// Equal reports whether both problem details have the same standard and extension members. The options that only
+// affect validation or encoding are not compared. Since it has the form of (T) Equal(T) bool, it is also used by
+// github.com/google/go-cmp, so cmp.Diff works on problem details without custom options.
+func (p *ProblemDetail) Equal(other *ProblemDetail) bool { return p.EqualIgnoring(other) }
+
+// EqualIgnoring is like Equal, but the named members are not compared, so a snapshot test can assert the stable part
+// of a problem detail without flaking on the generated ones, for example EqualIgnoring(other, "instance", "timestamp").
+// A name is either a standard member, such as "instance", or an extension member.
+func (p *ProblemDetail) EqualIgnoring(other *ProblemDetail, members ...string) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	ignored := make(map[string]struct{}, len(members))
+	for _, name := range members {
+		ignored[name] = struct{}{}
+	}
+	same := func(name string, a, b any) bool {
+		_, ok := ignored[name]
+		return ok || a == b
+	}
+	return same("type", p.Type, other.Type) &&
+		same("title", p.Title, other.Title) &&
+		same("status", p.Status, other.Status) &&
+		same("detail", p.Detail, other.Detail) &&
+		same("instance", p.Instance, other.Instance) &&
+		equalExtensions(p.extensions, other.extensions, ignored)
+}
+
+// equalExtensions reports whether both sets of extension members are deeply equal, except the ignored ones. A nil
+// set is equal to an empty one.
+func equalExtensions(a, b map[string]any, ignored map[string]struct{}) bool {
+	count := func(m map[string]any) int {
+		n := 0
+		for name := range m {
+			if _, ok := ignored[name]; !ok {
+				n++
+			}
+		}
+		return n
+	}
+	if count(a) != count(b) {
+		return false
+	}
+	for name, value := range a {
+		if _, ok := ignored[name]; ok {
+			continue
+		}
+		other, ok := b[name]
+		if !ok || !reflect.DeepEqual(value, other) {
+			return false
+		}
+	}
+	return true
+}
+
 // WriteStatus writes the status code to ProblemDetail.Status. If ProblemDetail.Type is Untyped, ProblemDetail.Title
 // will be updated with the status text. For example, if the status code is 404, the title will be "Not Found",
 // which is the status text for 404 (http.StatusText(404)). Otherwise, the title will be left unchanged.
 func (p *ProblemDetail) WriteStatus(code int) {
 	p.Status = code
 	if p.Type == Untyped {
-		p.Title = http.StatusText(code)
+		p.Title = statusTitle(code)
 	}
 }
 
 // Validate validates the problem detail based on the validation level. If the validation level is 0, no validation
 // is performed. Default validation level is LStrict.
+//
+// Errors of misused options, such as ErrReservedExtension, are always returned regardless of the validation level.
 func (p *ProblemDetail) Validate() error {
 	return errors.Join(
+		p.validateOptions(),
 		p.validateType(),
 		p.validateTitle(),
 		p.validateStatus(),
 		p.validateDetail(),
 		p.validateInstance(),
+		p.validateTypeInstance(),
+		p.validateExtensions(),
+		p.validateExtensionCount(),
+		p.validateControlChars(),
+	)
+}
+
+// validateConstruction validates the members that are set at construction time, which are all but
+// ProblemDetail.Status and the members that are filled at write time, see writeTimeLevels.
+func (p *ProblemDetail) validateConstruction() error {
+	c := *p
+	c.flags &^= p.writeTimeLevels()
+	return errors.Join(
+		c.validateOptions(),
+		c.validateType(),
+		c.validateTitle(),
+		c.validateDetail(),
+		c.validateInstance(),
+		c.validateTypeInstance(),
+		c.validateExtensions(),
+		c.validateExtensionCount(),
+		c.validateControlChars(),
 	)
 }
 
+// writeTimeLevels returns the checks of the required members that the writers may fill, so they cannot be checked at
+// construction time: the title of an untyped problem, or of one with WithTitleFromStatus, the detail of one with a
+// detail template or translations, and the type, the title and the instance of one with WithDefaults.
+func (p *ProblemDetail) writeTimeLevels() ValidateLevel {
+	var levels ValidateLevel
+	if p.Type == Untyped || p.titleFromStatus {
+		levels |= LTitleRequired
+	}
+	if p.detailTemplate != "" || len(p.translations) > 0 {
+		levels |= LDetailRequired
+	}
+	if p.defaults {
+		levels |= LTypeRequired | LTitleRequired | LInstanceRequired
+	}
+	return levels
+}
+
+func (p *ProblemDetail) validateTypeInstance() error {
+	if p.flags.has(LTypeInstanceDistinct) && p.Type != "" && p.Type == p.Instance {
+		return ErrTypeInstanceSame
+	}
+	return nil
+}
+
+func (p *ProblemDetail) validateControlChars() error {
+	if !p.flags.has(LControlChars) {
+		return nil
+	}
+
+	var errs []error
+	members := []struct{ name, value string }{
+		{name: "type", value: p.Type},
+		{name: "title", value: p.Title},
+		{name: "detail", value: p.Detail},
+		{name: "instance", value: p.Instance},
+	}
+	for _, m := range members {
+		if strings.IndexFunc(m.value, unicode.IsControl) >= 0 {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrControlChars, m.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateOptions returns the errors recorded while applying the options, and the standard members set more than once
+// when WithStrictOptions is used.
+func (p *ProblemDetail) validateOptions() error {
+	errs := p.errs
+	if p.strictOptions {
+		seen := make(map[string]int, len(p.applied))
+		for _, name := range p.applied {
+			seen[name]++
+			if seen[name] == 2 {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrDuplicateOption, name))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// markApplied records that an option sets the given standard member.
+func (p *ProblemDetail) markApplied(name string) { p.applied = append(p.applied, name) }
+
 func (p *ProblemDetail) validateType() error {
 	if p.flags.has(LTypeRequired) && p.Type == "" {
 		return ErrTypeRequired
@@ -155,6 +514,9 @@ func (p *ProblemDetail) validateTitle() error {
 }
 
 func (p *ProblemDetail) validateStatus() error {
+	if p.noStatus {
+		return nil
+	}
 	if p.flags.has(LStatusRequired) && (p.Status <= 0 || p.Status >= 600) {
 		return ErrStatusRequired
 	}
@@ -162,7 +524,8 @@ func (p *ProblemDetail) validateStatus() error {
 }
 
 func (p *ProblemDetail) validateDetail() error {
-	if p.flags.has(LDetailRequired) && p.Detail == "" {
+	required := p.flags.has(LDetailRequired) || (p.detailOn5xx && p.Status >= 500)
+	if required && p.Detail == "" {
 		return ErrDetailRequired
 	}
 	return nil
@@ -183,12 +546,13 @@ func (p *ProblemDetail) validateInstance() error {
 	return nil
 }
 
-// validationLevel is bitfield for validation level.
-type validationLevel uint8
+// ValidateLevel is bitfield for validation level. The flags can be combined to select the checks to run, for example
+// LStandard | VCheckURIFormat, or using one of the presets: LStandard, LAllRequired and LStrict.
+type ValidateLevel uint16
 
 const (
 	// LTypeRequired is to ensure that ProblemDetail.Type is not empty.
-	LTypeRequired validationLevel = 1 << iota
+	LTypeRequired ValidateLevel = 1 << iota
 
 	// LTitleRequired is to ensure that ProblemDetail.Title is not empty.
 	LTitleRequired
@@ -208,51 +572,230 @@ const (
 	// LInstanceFormat is to ensure that ProblemDetail.Instance is a valid URI.
 	LInstanceFormat
 
+	// LExtensionFormat is to ensure that all extension members can be encoded as JSON.
+	LExtensionFormat
+
+	// LControlChars is to ensure that ProblemDetail.Type, ProblemDetail.Title, ProblemDetail.Detail and
+	// ProblemDetail.Instance do not contain control characters, such as a line break.
+	LControlChars
+
+	// LTypeInstanceDistinct is to ensure that ProblemDetail.Type and ProblemDetail.Instance are not the same, which is
+	// usually a copy-paste mistake, since the type identifies the kind of problem and the instance its occurrence.
+	LTypeInstanceDistinct
+
+	// LExtensionSchema is to ensure that the extension members conform to the schema registered for the type by
+	// RegisterExtensionSchema, if any. It is checked by the writers, since the members are checked as they are encoded.
+	LExtensionSchema
+
 	// LStandard is the standard validation level based on RFC 7807.
 	LStandard = LTypeRequired | LTitleRequired | LStatusRequired
 
 	// LAllRequired is to ensure that all fields are not empty.
 	LAllRequired = LStandard | LDetailRequired | LInstanceRequired
 
-	// LStrict is to ensure that all fields are not empty and all URIs are valid.
-	LStrict = LAllRequired | LTypeFormat | LInstanceFormat
+	// LStrict is to ensure that all fields are not empty, all URIs are valid and all extension members can be encoded
+	// and conform to their registered schema. The type and the instance must also be distinct.
+	LStrict = LAllRequired | LTypeFormat | LInstanceFormat | LExtensionFormat | LTypeInstanceDistinct | LExtensionSchema
 )
 
+// Set of flags that group the checks by kind, to be combined by WithValidateFlags.
+const (
+	// VRequireFields is to ensure that all fields are not empty, the same as LAllRequired.
+	VRequireFields = LAllRequired
+
+	// VCheckURIFormat is to ensure that ProblemDetail.Type and ProblemDetail.Instance are valid URIs.
+	VCheckURIFormat = LTypeFormat | LInstanceFormat
+
+	// VCheckControlChars is to ensure that no member contains control characters, the same as LControlChars.
+	VCheckControlChars = LControlChars
+)
+
+// ValidationLevel returns the validation level used by ProblemDetail.Validate, as set by WithValidateLevel. Since the
+// method is promoted, a type that embeds *ProblemDetail reports the level of the embedded problem detail.
+func (p *ProblemDetail) ValidationLevel() ValidateLevel { return p.flags }
+
 // has returns true if the flag has the given flag.
-func (l validationLevel) has(flag validationLevel) bool { return l&flag != 0 }
+func (l ValidateLevel) has(flag ValidateLevel) bool { return l&flag != 0 }
 
 // WithValidateLevel sets the validation level of the ProblemDetail.
-func WithValidateLevel(level validationLevel) Option {
+func WithValidateLevel(level ValidateLevel) Option {
 	return func(pd *ProblemDetail) { pd.flags = level }
 }
 
+// WithValidateFlags sets the validation level of the ProblemDetail to the combination of the given flags, for example
+// WithValidateFlags(VRequireFields, VCheckControlChars) requires all fields and rejects control characters, but skips
+// the URI format checks.
+func WithValidateFlags(flags ...ValidateLevel) Option {
+	return func(pd *ProblemDetail) {
+		pd.flags = 0
+		for _, flag := range flags {
+			pd.flags |= flag
+		}
+	}
+}
+
+// WithRequireDetailOn5xx requires ProblemDetail.Detail for server errors, the status code 500 and above, regardless
+// of the validation level. It is independent of WithValidateLevel, so the order of both options does not matter.
+func WithRequireDetailOn5xx() Option {
+	return func(pd *ProblemDetail) { pd.detailOn5xx = true }
+}
+
+// WithSuppressStatusInBody omits the status member from the JSON and XML bodies, for consumers that treat the status
+// line as authoritative. The status code is still written to the status line and stored in ProblemDetail.Status, so
+// the validation of the status is unchanged.
+func WithSuppressStatusInBody() Option {
+	return func(pd *ProblemDetail) {
+		pd.suppressStatus = true
+		pd.xml.omitStatus = true
+	}
+}
+
+// WithStatusAsString makes the JSON writers write the status member as a string, such as "403", instead of a number.
+// It is not standard, RFC 9457 defines the status as a number, it is a compatibility shim for the consumers that
+// cannot be fixed. The XML body is not affected, its status is text anyway.
+func WithStatusAsString() Option {
+	return func(pd *ProblemDetail) { pd.statusAsString = true }
+}
+
+// WithStrictOptions makes the validation report ErrDuplicateOption when a standard member is set by more than one
+// option, for example WithTitle applied twice, to catch copy-paste mistakes in the construction. Without it the last
+// option wins. It can be given at any position in the options, the options applied before it are also checked.
+//
+// The error is returned regardless of the validation level, like the other errors of misused options.
+func WithStrictOptions() Option {
+	return func(pd *ProblemDetail) { pd.strictOptions = true }
+}
+
+// WithoutStatus marks the status as intentionally absent, for a problem detail used outside HTTP, such as the error of
+// a message consumer, where there is no status code to report. The status is not required by the validation, whatever
+// the validation level, and it is omitted from the JSON and XML bodies, like with WithSuppressStatusInBody.
+func WithoutStatus() Option {
+	return func(pd *ProblemDetail) {
+		pd.noStatus = true
+		pd.suppressStatus = true
+		pd.xml.omitStatus = true
+	}
+}
+
 // WithTitle sets the title of the ProblemDetail.
 func WithTitle(title string) Option {
-	return func(pd *ProblemDetail) { pd.Title = title }
+	return func(pd *ProblemDetail) {
+		pd.markApplied("title")
+		pd.Title = title
+	}
 }
 
 // WithDetail sets the detail of the ProblemDetail.
 func WithDetail(detail string) Option {
-	return func(pd *ProblemDetail) { pd.Detail = detail }
+	return func(pd *ProblemDetail) {
+		pd.markApplied("detail")
+		pd.Detail = detail
+	}
+}
+
+// WithDetailMaxBytes caps ProblemDetail.Detail to n bytes at write time, so a huge error message, for example of a
+// wrapped error, does not bloat the response. A longer detail is cut at a rune boundary and ends with the "…" marker,
+// the marker included, it is at most n bytes. When n is 0, the detail is not capped.
+func WithDetailMaxBytes(n int) Option {
+	return func(pd *ProblemDetail) { pd.detailMaxBytes = n }
+}
+
+// truncationMarker ends a detail that is cut by WithDetailMaxBytes.
+const truncationMarker = "…"
+
+// truncateDetail caps ProblemDetail.Detail to the size set by WithDetailMaxBytes.
+func (p *ProblemDetail) truncateDetail() {
+	n := p.detailMaxBytes
+	if n <= 0 || len(p.Detail) <= n {
+		return
+	}
+
+	marker := truncationMarker
+	if n < len(marker) {
+		marker = ""
+	}
+	cut := n - len(marker)
+	for cut > 0 && !utf8.RuneStart(p.Detail[cut]) {
+		cut--
+	}
+	p.Detail = p.Detail[:cut] + marker
 }
 
 // WithInstance sets the instance of the ProblemDetail.
 func WithInstance(instance string) Option {
-	return func(pd *ProblemDetail) { pd.Instance = instance }
+	return func(pd *ProblemDetail) {
+		pd.markApplied("instance")
+		pd.Instance = instance
+	}
+}
+
+// WithInstancef sets the instance of the ProblemDetail from a format specifier, for example
+// WithInstancef("/orders/%d", id). Unlike WithInstance, the formatted instance is checked right away, an invalid URI
+// reference, such as one that contains a control character, is reported as ErrInstanceFormat regardless of the
+// validation level.
+func WithInstancef(format string, args ...any) Option {
+	return func(pd *ProblemDetail) {
+		pd.markApplied("instance")
+		pd.Instance = fmt.Sprintf(format, args...)
+		if _, err := url.Parse(pd.Instance); err != nil {
+			pd.errs = append(pd.errs, errors.Join(ErrInstanceFormat, err))
+		}
+	}
 }
 
 // WriteJSON writes the problem detail to the response writer as JSON.
 // The content type is set to application/problem+json; charset=utf-8.
-// The status code will be set to both ProblemDetail.Status and http.ResponseWriter.
+// The status code will be set to both ProblemDetail.Status and http.ResponseWriter. Any status code is accepted, so a
+// problem detail can also describe a non-error outcome, such as 200 or 201, with the same content type.
+//
+// If pd implements json.Marshaler, for example to keep a legacy layout, its encoding is written as it is, followed by
+// the extension members if it is an object.
 //
 // If the problem detail is invalid, an error is returned.
 func WriteJSON(w http.ResponseWriter, pd ProblemDetailer, code int) error {
+	return defaultWriter.JSON(w, pd, code)
+}
+
+// WriteJSONIndent is like WriteJSON, but the JSON is indented as json.MarshalIndent does: every element begins on a
+// new line starting with prefix, followed by one or more copies of indent according to its nesting. The extension
+// members, including nested maps and structs, are indented the same way. It is meant for human readers, such as
+// fixture files, the compact WriteJSON is preferred on the wire.
+func WriteJSONIndent(w http.ResponseWriter, pd ProblemDetailer, code int, prefix, indent string) error {
+	f := formatJSON
+	f.name = "WriteJSONIndent"
+	f.encode = func(pd ProblemDetailer) ([]byte, error) {
+		raw, err := encodeJSON(pd)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, raw, prefix, indent); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return write(w, nil, pd, code, f)
+}
+
+// DryRunJSON prepares, validates and encodes the problem detail as WriteJSON does, but returns the body and the headers
+// that WriteJSON would write instead of writing them, for example to gate the response on its size. The headers
+// include the content type, the headers set by the options and the ETag header of WithETag. Like WriteJSON, the status
+// code is written to ProblemDetail.Status.
+//
+// If the problem detail is invalid, an error is returned.
+func DryRunJSON(pd ProblemDetailer, code int) ([]byte, http.Header, error) {
 	pd.WriteStatus(code)
-	if err := pd.Validate(); err != nil {
-		return fmt.Errorf("WriteJSON: %w", err)
+	body, err := encode(pd, encodeJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("DryRunJSON: %w", err)
+	}
+	h := make(http.Header)
+	writeHeaders(h, pd, "")
+	if tag, ok := etagOf(pd, body); ok {
+		h.Set("ETag", tag)
 	}
-	writeContentTypeAndStatus(w, "application/problem+json; charset=utf-8", code)
-	return json.NewEncoder(w).Encode(pd)
+	h.Set("Content-Type", formatJSON.contentType)
+	return body, h, nil
 }
 
 // WriteXML writes the problem detail to the response writer as XML.
@@ -261,16 +804,199 @@ func WriteJSON(w http.ResponseWriter, pd ProblemDetailer, code int) error {
 //
 // If the problem detail is invalid, an error is returned.
 func WriteXML(w http.ResponseWriter, pd ProblemDetailer, code int) error {
+	return defaultWriter.XML(w, pd, code)
+}
+
+// format describes how a problem detail is serialized by the writers.
+type format struct {
+	// name is the name of the public writer, used as the error prefix.
+	name string
+
+	// contentType is the value of the Content-Type header.
+	contentType string
+
+	// encode serializes the problem detail into the response body.
+	encode func(pd ProblemDetailer) ([]byte, error)
+
+	// language is the key of the translation negotiated by Write, empty means the problem detail is not translated.
+	language string
+}
+
+var (
+	formatJSON = format{name: "WriteJSON", contentType: "application/problem+json; charset=utf-8", encode: encodeJSON}
+	formatXML  = format{name: "WriteXML", contentType: "application/problem+xml; charset=utf-8", encode: encodeXML}
+)
+
+// write prepares, validates and encodes the problem detail before committing the response. The body is fully
+// encoded before the status code is written, so an encoding error never leaves a half-written response. Once written,
+// the response is flushed. A response that is already committed, as reported by AlreadyWritten, is rejected with
+// ErrAlreadyWritten. The request is optional, it is only used for conditional requests.
+func write(w http.ResponseWriter, r *http.Request, pd ProblemDetailer, code int, f format) error {
+	if AlreadyWritten(w) {
+		return fmt.Errorf("%s: %w", f.name, stageError(ErrIO, ErrAlreadyWritten))
+	}
 	pd.WriteStatus(code)
-	if err := pd.Validate(); err != nil {
-		return fmt.Errorf("WriteXML: %w", err)
+	body, restore, err := encodeIn(pd, f.language, f.encode)
+	defer restore()
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	writeHeaders(w.Header(), pd, f.language)
+	if notModified(w, r, pd, code, body) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	writeContentTypeAndStatus(w, f.contentType, code)
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("%s: %w", f.name, stageError(ErrIO, err))
+	}
+	if err := flush(w); err != nil {
+		return fmt.Errorf("%s: %w", f.name, stageError(ErrIO, err))
+	}
+	return nil
+}
+
+// encode prepares and validates the problem detail, then encodes it with the given encoder. A failure of the
+// preparation or the validation is wrapped with ErrValidation, a failure of the encoder with ErrMarshal.
+func encode(pd ProblemDetailer, encoder func(pd ProblemDetailer) ([]byte, error)) ([]byte, error) {
+	body, _, err := encodeIn(pd, "", encoder)
+	return body, err
+}
+
+// encodeIn is like encode, but the problem detail is encoded in the language of the given translation key, if any.
+// The returned function restores the members changed by the translation, it must be called once the write is done.
+func encodeIn(
+	pd ProblemDetailer,
+	language string,
+	encoder func(pd ProblemDetailer) ([]byte, error),
+) (body []byte, restore func(), err error) {
+	restore, err = prepare(pd, language)
+	if err != nil {
+		return nil, restore, stageError(ErrValidation, err)
+	}
+	if err := validate(pd); err != nil {
+		return nil, restore, stageError(ErrValidation, err)
+	}
+	body, err = encoder(pd)
+	if err != nil {
+		return nil, restore, stageError(ErrMarshal, err)
+	}
+	return body, restore, nil
+}
+
+// EncodeJSON writes the JSON encoding of the problem detail to w, for example to snapshot a problem to a file. The
+// output is byte-identical to the body written by WriteJSON, but ProblemDetail.Status is kept as it is, since there
+// is no status code to write.
+//
+// If the problem detail is invalid, an error is returned and nothing is written to w.
+func EncodeJSON(w io.Writer, pd ProblemDetailer) error {
+	body, err := encode(pd, encodeJSON)
+	if err != nil {
+		return fmt.Errorf("EncodeJSON: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("EncodeJSON: %w", stageError(ErrIO, err))
+	}
+	return nil
+}
+
+// EncodeXML writes the XML encoding of the problem detail to w. The output is byte-identical to the body written by
+// WriteXML, but ProblemDetail.Status is kept as it is, since there is no status code to write.
+//
+// If the problem detail is invalid, an error is returned and nothing is written to w.
+func EncodeXML(w io.Writer, pd ProblemDetailer) error {
+	body, err := encode(pd, encodeXML)
+	if err != nil {
+		return fmt.Errorf("EncodeXML: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("EncodeXML: %w", stageError(ErrIO, err))
+	}
+	return nil
+}
+
+// JSON validates the problem detail and returns its JSON encoding, the same as EncodeJSON writes, for tests and logs.
+// Since the method is promoted, on a type that embeds ProblemDetail it only encodes the embedded problem detail, use
+// EncodeJSON to include the fields of the embedding type.
+func (p *ProblemDetail) JSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeJSON(&buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// XML validates the problem detail and returns its XML encoding, the same as EncodeXML writes. Like JSON, on a type
+// that embeds ProblemDetail it only encodes the embedded problem detail.
+func (p *ProblemDetail) XML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeXML(&buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flush sends the buffered response to the client right away, so the problem is not held back by buffering
+// proxies. It is a no-op if the response writer does not support flushing.
+func flush(w http.ResponseWriter) error {
+	err := http.NewResponseController(w).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+// prepare resolves the write-time members of the problem detail, such as the nonce, the detail template, the
+// translation of the given key, the defaults, the type base URL and the maximum size of the detail. The returned
+// function restores the members changed by the translation, it is never nil.
+func prepare(pd ProblemDetailer, language string) (restore func(), err error) {
+	restore = func() {}
+	p := baseOf(pd)
+	if p == nil {
+		return restore, nil
+	}
+	if err := p.renewNonce(); err != nil {
+		return restore, err
+	}
+	if err := p.renderDetail(); err != nil {
+		return restore, err
+	}
+	if p.titleFromStatus {
+		p.Title = statusTitle(p.Status)
+	}
+	restore = p.translate(language)
+	p.deriveTitleFromDetail()
+	if err := p.fillDefaults(); err != nil {
+		return restore, err
+	}
+	p.resolveTypeBase()
+	p.truncateDetail()
+	return restore, nil
+}
+
+// writeHeaders applies the headers that are set by the options of the problem detail, and the Content-Language header
+// of the translation of the given key, if any. With WithScrubHeaders, the managed headers are deleted first.
+func writeHeaders(h http.Header, pd ProblemDetailer, language string) {
+	p := baseOf(pd)
+	if p == nil {
+		return
+	}
+	if p.scrubHeaders {
+		for _, key := range managedHeaders {
+			h.Del(key)
+		}
+	}
+	if t, ok := p.translations[language]; ok && language != "" {
+		h.Set("Content-Language", t.tag)
+	}
+	for _, apply := range p.headers {
+		apply(h, p)
 	}
-	writeContentTypeAndStatus(w, "application/problem+xml; charset=utf-8", code)
-	return xml.NewEncoder(w).Encode(pd)
 }
 
-// writeContentTypeAndStatus writes the content type and status code to the response writer.
+// writeContentTypeAndStatus writes the content type and status code to the response writer. The content type replaces
+// any content type set before, such as by a handler that failed after choosing its own.
 func writeContentTypeAndStatus(w http.ResponseWriter, value string, code int) {
-	w.Header().Add("Content-Type", value)
+	w.Header().Set("Content-Type", value)
 	w.WriteHeader(code)
 }
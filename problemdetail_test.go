@@ -1,12 +1,20 @@
 package problemdetail_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/josestg/problemdetail"
 )
 
@@ -41,6 +49,72 @@ func TestWriteJSON_WithExtension(t *testing.T) {
 	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
 }
 
+// LegacyProblemDetail is a sample problem detail that keeps the layout of a legacy error format.
+type LegacyProblemDetail struct {
+	*problemdetail.ProblemDetail
+}
+
+func (l *LegacyProblemDetail) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"error_code": l.Status,
+		"message":    l.Title,
+	})
+}
+
+// errNegativeBalance is returned by ValidatedBalanceProblemDetail.ValidateProblem.
+var errNegativeBalance = errors.New("balance must not be negative")
+
+// ValidatedBalanceProblemDetail is a sample problem detail that validates its own fields.
+type ValidatedBalanceProblemDetail struct {
+	*problemdetail.ProblemDetail
+	Balance int32 `json:"balance" xml:"balance"`
+}
+
+func (v *ValidatedBalanceProblemDetail) ValidateProblem() error {
+	if v.Balance < 0 {
+		return errNegativeBalance
+	}
+	return nil
+}
+
+func TestWriteJSON_WithValidator(t *testing.T) {
+	newData := func(balance int32) *ValidatedBalanceProblemDetail {
+		return &ValidatedBalanceProblemDetail{
+			ProblemDetail: problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard)),
+			Balance:       balance,
+		}
+	}
+
+	err := problemdetail.WriteJSON(httptest.NewRecorder(), newData(30), 403)
+	expectTrue(t, err == nil)
+
+	rec := httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, newData(-1), 403)
+	expectTrue(t, errors.Is(err, errNegativeBalance))
+	expectTrue(t, errors.Is(err, problemdetail.ErrValidation))
+	expectTrue(t, rec.Body.Len() == 0)
+
+	err = problemdetail.WriteXML(httptest.NewRecorder(), newData(-1), 403)
+	expectTrue(t, errors.Is(err, errNegativeBalance))
+}
+
+func TestWriteJSON_WithCustomMarshaler(t *testing.T) {
+	data := LegacyProblemDetail{
+		ProblemDetail: problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard)),
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, &data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"error_code":403,"message":"Forbidden"}`)
+
+	problemdetail.WithExtension("balance", 30)(data.ProblemDetail)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, &data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"error_code":403,"message":"Forbidden","balance":30}`)
+}
+
 func TestWriteJSON_WithUntyped(t *testing.T) {
 	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
 
@@ -76,6 +150,227 @@ func TestWriteJSON_WithTyped(t *testing.T) {
 	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
 }
 
+func TestWriteJSON_WithSuccessStatus(t *testing.T) {
+	for _, code := range []int{200, 201} {
+		data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+		rec := httptest.NewRecorder()
+		err := problemdetail.WriteJSON(rec, data, code)
+		expectTrue(t, err == nil)
+
+		expRaw := fmt.Sprintf(`{"type":"about:blank","title":%q,"status":%d}`, http.StatusText(code), code)
+		gotRaw := strings.TrimSpace(rec.Body.String())
+
+		expectTrue(t, gotRaw == expRaw)
+		expectTrue(t, rec.Code == code)
+		expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+	}
+}
+
+// nonFlusher is a response writer that does not support flushing.
+type nonFlusher struct{ http.ResponseWriter }
+
+// brokenWriter is a response writer whose body cannot be written, like a connection closed by the client.
+type brokenWriter struct{ http.ResponseWriter }
+
+func (brokenWriter) Write([]byte) (int, error) { return 0, io.ErrClosedPipe }
+
+// BrokenProblemDetail is a sample problem detail whose JSON encoding fails.
+type BrokenProblemDetail struct {
+	*problemdetail.ProblemDetail
+}
+
+func (b *BrokenProblemDetail) MarshalJSON() ([]byte, error) { return nil, io.ErrUnexpectedEOF }
+
+func TestWriteJSON_ErrorStage(t *testing.T) {
+	newPD := func() *problemdetail.ProblemDetail {
+		return problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+	}
+
+	invalid := problemdetail.New("", problemdetail.WithValidateLevel(problemdetail.LStandard))
+	err := problemdetail.WriteJSON(httptest.NewRecorder(), invalid, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrValidation))
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrMarshal) && !errors.Is(err, problemdetail.ErrIO))
+	expectTrue(t, strings.HasPrefix(err.Error(), "WriteJSON: validate: "))
+
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), &BrokenProblemDetail{ProblemDetail: newPD()}, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrMarshal))
+	expectTrue(t, errors.Is(err, io.ErrUnexpectedEOF))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrValidation) && !errors.Is(err, problemdetail.ErrIO))
+
+	err = problemdetail.WriteJSON(brokenWriter{httptest.NewRecorder()}, newPD(), 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrIO))
+	expectTrue(t, errors.Is(err, io.ErrClosedPipe))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrValidation) && !errors.Is(err, problemdetail.ErrMarshal))
+}
+
+func TestWriteJSON_Flush(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Flushed)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(nonFlusher{rec}, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, !rec.Flushed)
+	expectTrue(t, rec.Code == 403)
+}
+
+func TestSetTrailingNewline(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	problemdetail.SetTrailingNewline(false)
+	t.Cleanup(func() { problemdetail.SetTrailingNewline(true) })
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Body.String() == `{"type":"about:blank","title":"Forbidden","status":403}`)
+
+	problemdetail.SetTrailingNewline(true)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Body.String() == `{"type":"about:blank","title":"Forbidden","status":403}`+"\n")
+}
+
+func TestSetEscapeHTML(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail("Use <a> & <b>."),
+		problemdetail.WithExtension("see", "https://example.com/search?q=a&page=2"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 400)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Use \u003ca\u003e \u0026 \u003cb\u003e.","see":"https://example.com/search?q=a\u0026page=2"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+
+	problemdetail.SetEscapeHTML(false)
+	t.Cleanup(func() { problemdetail.SetEscapeHTML(true) })
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 400)
+	expectTrue(t, err == nil)
+
+	expRaw = `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Use <a> & <b>.","see":"https://example.com/search?q=a&page=2"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+}
+
+func TestEncodeJSON(t *testing.T) {
+	newPD := func() *BalanceProblemDetail {
+		return &BalanceProblemDetail{
+			ProblemDetail: problemdetail.New(
+				"https://example.com/probs/out-of-credit",
+				problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+				problemdetail.WithInstance("/account/12345/abc"),
+				problemdetail.WithTitle("You do not have enough credit."),
+				problemdetail.WithExtension("cost", 50),
+			),
+			Balance:  30,
+			Accounts: []string{"/account/12345", "/account/67890"},
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, newPD(), 403)
+	expectTrue(t, err == nil)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "problem.json"))
+	expectTrue(t, err == nil)
+	defer f.Close()
+
+	pd := newPD()
+	pd.WriteStatus(403)
+	err = problemdetail.EncodeJSON(f, pd)
+	expectTrue(t, err == nil)
+
+	got, err := os.ReadFile(f.Name())
+	expectTrue(t, err == nil)
+	expectTrue(t, bytes.Equal(got, rec.Body.Bytes()))
+}
+
+func TestEncodeJSON_WithInvalidProblem(t *testing.T) {
+	var buf bytes.Buffer
+	err := problemdetail.EncodeJSON(&buf, problemdetail.New(""))
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, buf.Len() == 0)
+}
+
+func TestEncodeJSON_WithoutStatus(t *testing.T) {
+	pd := problemdetail.New("https://example.com/probs/message-rejected",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("The message was rejected."),
+		problemdetail.WithoutStatus(),
+	)
+
+	var buf bytes.Buffer
+	err := problemdetail.EncodeJSON(&buf, pd)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(buf.String()) == `{"type":"https://example.com/probs/message-rejected","title":"The message was rejected."}`)
+
+	buf.Reset()
+	err = problemdetail.EncodeXML(&buf, pd)
+	expectTrue(t, err == nil)
+	expectTrue(t, buf.String() == `<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/probs/message-rejected</type><title>The message was rejected.</title></problem>`)
+
+	pd = problemdetail.New("https://example.com/probs/message-rejected",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("The message was rejected."),
+	)
+	err = problemdetail.EncodeJSON(&buf, pd)
+	expectTrue(t, errors.Is(err, problemdetail.ErrStatusRequired))
+}
+
+func TestEncodeXML(t *testing.T) {
+	newPD := func() *problemdetail.ProblemDetail {
+		pd := problemdetail.New("https://example.com/probs/out-of-credit",
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithExtension("balance", 30),
+		)
+		pd.WriteStatus(403)
+		return pd
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, newPD(), 403)
+	expectTrue(t, err == nil)
+
+	var buf bytes.Buffer
+	err = problemdetail.EncodeXML(&buf, newPD())
+	expectTrue(t, err == nil)
+	expectTrue(t, buf.String() == rec.Body.String())
+
+	buf.Reset()
+	err = problemdetail.EncodeXML(&buf, problemdetail.New(""))
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, buf.Len() == 0)
+}
+
+func TestProblemDetail_JSONAndXML(t *testing.T) {
+	pd := problemdetail.Errorf("https://example.com/probs/out-of-credit", 403, "balance is %d", 30)
+
+	raw, err := pd.JSON()
+	expectTrue(t, err == nil)
+	expectTrue(t, string(raw) == `{"type":"https://example.com/probs/out-of-credit","title":"Forbidden","status":403,"detail":"balance is 30"}`+"\n")
+
+	raw, err = pd.XML()
+	expectTrue(t, err == nil)
+	expectTrue(t, string(raw) == `<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/probs/out-of-credit</type><title>Forbidden</title><status>403</status><detail>balance is 30</detail></problem>`)
+
+	_, err = problemdetail.New("").JSON()
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	_, err = problemdetail.New("").XML()
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+}
+
 func TestWriteJSON_WithStrictButAllEmpty(t *testing.T) {
 	data := problemdetail.New("")
 
@@ -92,6 +387,89 @@ func TestWriteJSON_WithStrictButAllEmpty(t *testing.T) {
 	expectTrue(t, !errors.Is(err, problemdetail.ErrInstanceFormat))
 }
 
+func TestWriteJSON_WithRequireDetailOn5xx(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithRequireDetailOn5xx(),
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 503)
+	expectTrue(t, errors.Is(err, problemdetail.ErrDetailRequired))
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 404)
+	expectTrue(t, err == nil)
+
+	data.Detail = "The database is unreachable."
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 503)
+	expectTrue(t, err == nil)
+}
+
+func TestWriteJSON_WithSuppressStatusInBody(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithSuppressStatusInBody(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Forbidden"}`)
+	expectTrue(t, rec.Code == 403)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 0)
+	expectTrue(t, errors.Is(err, problemdetail.ErrStatusRequired))
+}
+
+func TestWriteJSON_WithStatusAsString(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithStatusAsString(),
+		problemdetail.WithExtension("balance", 30),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Forbidden","status":"403","balance":30}`)
+	expectTrue(t, rec.Code == 403)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.Contains(rec.Body.String(), "<status>403</status>"))
+}
+
+func TestWriteJSON_WithValidateFlags(t *testing.T) {
+	data := problemdetail.New("--not-\n/a/valid/uri--",
+		problemdetail.WithValidateFlags(problemdetail.VRequireFields),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithDetail("Your current balance is 30,\nbut that costs 50."),
+		problemdetail.WithInstance("/account/12345/abc"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	problemdetail.WithValidateFlags(problemdetail.VRequireFields, problemdetail.VCheckControlChars)(data)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrControlChars))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrTypeFormat))
+	expectTrue(t, strings.Contains(err.Error(), "type"))
+	expectTrue(t, strings.Contains(err.Error(), "detail"))
+
+	problemdetail.WithValidateFlags(problemdetail.LStandard, problemdetail.VCheckURIFormat)(data)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeFormat))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrControlChars))
+}
+
 func TestWriteJSON_WithTypedStrictButTypeAndInstanceInvalidFormat(t *testing.T) {
 	data := problemdetail.New("--not-\n/a/valid/uri--",
 		problemdetail.WithInstance("\n-not/a/valid/path\n"),
@@ -179,6 +557,19 @@ func TestWriteXML_WithStrictButAllEmpty(t *testing.T) {
 	expectTrue(t, !errors.Is(err, problemdetail.ErrInstanceFormat))
 }
 
+func TestWriteXML_WithSuppressStatusInBody(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithSuppressStatusInBody(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Forbidden</title></problem>`)
+	expectTrue(t, rec.Code == 403)
+}
+
 func TestWriteXML_WithTypedStrictButTypeAndInstanceInvalidFormat(t *testing.T) {
 	data := problemdetail.New("--not-\n/a/valid/uri--",
 		problemdetail.WithInstance("\n-not/a/valid/path\n"),
@@ -191,6 +582,46 @@ func TestWriteXML_WithTypedStrictButTypeAndInstanceInvalidFormat(t *testing.T) {
 	expectTrue(t, errors.Is(err, problemdetail.ErrInstanceFormat))
 }
 
+func TestWithDetailMaxBytes(t *testing.T) {
+	tests := []struct {
+		detail string
+		max    int
+		exp    string
+	}{
+		{detail: "database is unreachable", max: 0, exp: "database is unreachable"},
+		{detail: "database is unreachable", max: 23, exp: "database is unreachable"},
+		{detail: "database is unreachable", max: 11, exp: "database…"},
+		{detail: "データベース", max: 10, exp: "デー…"},
+		{detail: "データベース", max: 2, exp: ""},
+	}
+
+	for _, tt := range tests {
+		pd := problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithDetail(tt.detail),
+			problemdetail.WithDetailMaxBytes(tt.max),
+		)
+		rec := httptest.NewRecorder()
+		err := problemdetail.WriteJSON(rec, pd, 500)
+		expectTrue(t, err == nil)
+		expectTrue(t, pd.Detail == tt.exp)
+		expectTrue(t, tt.max == 0 || len(pd.Detail) <= tt.max)
+	}
+}
+
+func TestWithInstancef(t *testing.T) {
+	pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithInstancef("/orders/%d", 42))
+	expectTrue(t, pd.Instance == "/orders/42")
+
+	pd = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithInstancef("/orders/%s", "42\n"),
+	)
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, pd, 404)
+	expectTrue(t, errors.Is(err, problemdetail.ErrInstanceFormat))
+}
+
 func TestProblemDetail_Error(t *testing.T) {
 	pd := problemdetail.New("https://example.com/probs/out-of-credit",
 		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
@@ -206,6 +637,354 @@ func TestProblemDetail_Error(t *testing.T) {
 	expectTrue(t, pdErr.Error() == "problem detail: https://example.com/probs/out-of-credit")
 }
 
+func TestProblemDetail_IsUntyped(t *testing.T) {
+	expectTrue(t, problemdetail.New("").IsUntyped())
+	expectTrue(t, problemdetail.New(problemdetail.Untyped).IsUntyped())
+	expectTrue(t, !problemdetail.New("https://example.com/probs/out-of-credit").IsUntyped())
+}
+
+func TestErrorf(t *testing.T) {
+	err := error(problemdetail.Errorf("https://example.com/probs/out-of-credit", 403,
+		"Your current balance is %d, but that costs %d.", 30, 50))
+
+	var pd *problemdetail.ProblemDetail
+	expectTrue(t, errors.As(err, &pd))
+	expectTrue(t, pd.Status == 403)
+	expectTrue(t, pd.Title == "Forbidden")
+	expectTrue(t, pd.Detail == "Your current balance is 30, but that costs 50.")
+
+	rec := httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, pd, pd.Status)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"https://example.com/probs/out-of-credit","title":"Forbidden","status":403,"detail":"Your current balance is 30, but that costs 50."}`)
+}
+
+func TestP(t *testing.T) {
+	var pd *problemdetail.Problem = problemdetail.P("https://example.com/probs/out-of-credit",
+		problemdetail.WithTitle("You do not have enough credit."),
+	)
+	expectTrue(t, pd.Equal(problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithTitle("You do not have enough credit."),
+	)))
+}
+
+func TestNewStrict(t *testing.T) {
+	pd, err := problemdetail.NewStrict("https://example.com/probs/out-of-credit",
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+		problemdetail.WithInstance("/account/12345/abc"),
+		problemdetail.WithTitle("You do not have enough credit."),
+	)
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Type == "https://example.com/probs/out-of-credit")
+}
+
+func TestNewStrict_WithInvalidType(t *testing.T) {
+	pd, err := problemdetail.NewStrict("--not-\n/a/valid/uri--",
+		problemdetail.WithValidateLevel(problemdetail.LTypeFormat),
+	)
+	expectTrue(t, pd == nil)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeFormat))
+	expectTrue(t, !errors.Is(err, problemdetail.ErrStatusRequired))
+}
+
+func TestNewStrict_WriteTimeMembers(t *testing.T) {
+	const typ = "https://example.com/probs/out-of-credit"
+	tests := []struct {
+		typ  string
+		opts []problemdetail.Option
+	}{
+		{typ: problemdetail.Untyped, opts: []problemdetail.Option{problemdetail.WithValidateLevel(problemdetail.LStandard)}},
+		{typ: typ, opts: []problemdetail.Option{
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithTitleFromStatus(),
+		}},
+		{typ: typ, opts: []problemdetail.Option{
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithInstance("/account/12345/abc"),
+			problemdetail.WithExtension("balance", 30),
+			problemdetail.WithDetailTemplate("Your current balance is {{.balance}}."),
+		}},
+		{typ: typ, opts: []problemdetail.Option{
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithInstance("/account/12345/abc"),
+			problemdetail.WithTranslation("id", "Kredit Anda tidak cukup.", "Saldo Anda saat ini 30."),
+		}},
+		{typ: "", opts: []problemdetail.Option{
+			problemdetail.WithValidateLevel(problemdetail.LStandard | problemdetail.LInstanceRequired),
+			problemdetail.WithDefaults(),
+		}},
+	}
+
+	for _, tt := range tests {
+		pd, err := problemdetail.NewStrict(tt.typ, tt.opts...)
+		expectTrue(t, err == nil)
+		expectTrue(t, pd != nil)
+	}
+
+	_, err := problemdetail.NewStrict(typ, problemdetail.WithValidateLevel(problemdetail.LStandard))
+	expectTrue(t, errors.Is(err, problemdetail.ErrTitleRequired))
+
+	_, err = problemdetail.NewStrict(typ,
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithInstance("/account/12345/abc"),
+	)
+	expectTrue(t, errors.Is(err, problemdetail.ErrDetailRequired))
+}
+
+func TestProblemDetail_ValidateTypeInstanceSame(t *testing.T) {
+	newPD := func(level problemdetail.ValidateLevel) *problemdetail.ProblemDetail {
+		return problemdetail.New("https://example.com/probs/out-of-credit",
+			problemdetail.WithValidateLevel(level),
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+			problemdetail.WithInstance("https://example.com/probs/out-of-credit"),
+		)
+	}
+
+	err := problemdetail.WriteJSON(httptest.NewRecorder(), newPD(problemdetail.LStrict), 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeInstanceSame))
+
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), newPD(problemdetail.LStandard), 403)
+	expectTrue(t, err == nil)
+}
+
+func TestWriteJSON_EmbeddedValidationLevel(t *testing.T) {
+	newData := func(level problemdetail.ValidateLevel) *BalanceProblemDetail {
+		return &BalanceProblemDetail{
+			ProblemDetail: problemdetail.New("https://example.com/probs/out-of-credit",
+				problemdetail.WithValidateLevel(level),
+				problemdetail.WithTitle("You do not have enough credit."),
+			),
+			Balance: 30,
+		}
+	}
+
+	pd := newData(problemdetail.LStrict)
+	expectTrue(t, pd.ValidationLevel() == problemdetail.LStrict)
+	err := problemdetail.WriteJSON(httptest.NewRecorder(), pd, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrDetailRequired))
+	expectTrue(t, errors.Is(err, problemdetail.ErrInstanceRequired))
+
+	pd = newData(problemdetail.LStandard)
+	expectTrue(t, pd.ValidationLevel() == problemdetail.LStandard)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), pd, 403)
+	expectTrue(t, err == nil)
+}
+
+func TestProblemDetail_StatusClass(t *testing.T) {
+	tests := []struct {
+		status       int
+		is4xx, is5xx bool
+	}{
+		{status: 0},
+		{status: 200},
+		{status: 399},
+		{status: 400, is4xx: true},
+		{status: 499, is4xx: true},
+		{status: 500, is5xx: true},
+		{status: 599, is5xx: true},
+		{status: 600},
+	}
+	for _, tt := range tests {
+		pd := problemdetail.New(problemdetail.Untyped)
+		pd.Status = tt.status
+		expectTrue(t, pd.StatusCode() == tt.status)
+		expectTrue(t, pd.Is4xx() == tt.is4xx)
+		expectTrue(t, pd.Is5xx() == tt.is5xx)
+	}
+}
+
+func TestMustNew(t *testing.T) {
+	level := problemdetail.WithValidateFlags(problemdetail.LStandard, problemdetail.LTypeFormat)
+	pd := problemdetail.MustNew("https://example.com/probs/out-of-credit",
+		level,
+		problemdetail.WithTitle("You do not have enough credit."),
+	)
+	expectTrue(t, pd.Type == "https://example.com/probs/out-of-credit")
+
+	defer func() {
+		r := recover()
+		msg, ok := r.(string)
+		expectTrue(t, ok)
+		expectTrue(t, strings.HasPrefix(msg, `problemdetail: MustNew("example.com/probs out of credit"): `))
+		expectTrue(t, strings.Contains(msg, problemdetail.ErrTypeFormat.Error()))
+	}()
+	problemdetail.MustNew("example.com/probs out of credit", level, problemdetail.WithTitle("You do not have enough credit."))
+	t.Fatal("expected a panic")
+}
+
+// catalogNotFound is an untyped entry of a package-level catalog, its title is the status text set at write time.
+var catalogNotFound = problemdetail.MustNew(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+func TestMustNew_UntypedCatalogEntry(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, catalogNotFound, 404)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Not Found","status":404}`)
+}
+
+func TestProblemDetail_Equal(t *testing.T) {
+	newPD := func(opts ...problemdetail.Option) *problemdetail.ProblemDetail {
+		opts = append([]problemdetail.Option{
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithExtension("balance", 30),
+		}, opts...)
+		return problemdetail.New("https://example.com/probs/out-of-credit", opts...)
+	}
+
+	expectTrue(t, newPD().Equal(newPD()))
+	expectTrue(t, newPD().Equal(newPD(problemdetail.WithValidateLevel(problemdetail.LStandard))))
+	expectTrue(t, !newPD().Equal(newPD(problemdetail.WithDetail("Your current balance is 30, but that costs 50."))))
+	expectTrue(t, !newPD().Equal(newPD(problemdetail.WithExtension("balance", 20))))
+	expectTrue(t, !newPD().Equal(nil))
+	expectTrue(t, (*problemdetail.ProblemDetail)(nil).Equal(nil))
+}
+
+func TestProblemDetail_EqualWithCmp(t *testing.T) {
+	newPD := func(opts ...problemdetail.Option) *problemdetail.ProblemDetail {
+		opts = append([]problemdetail.Option{
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithExtension("balance", 30),
+		}, opts...)
+		return problemdetail.New("https://example.com/probs/out-of-credit", opts...)
+	}
+
+	expectTrue(t, cmp.Diff(newPD(), newPD(problemdetail.WithValidateLevel(problemdetail.LStandard))) == "")
+	expectTrue(t, cmp.Diff(newPD(), newPD(problemdetail.WithExtension("balance", 20))) != "")
+
+	a := BalanceProblemDetail{ProblemDetail: newPD(), Balance: 30, Accounts: []string{"/account/12345"}}
+	b := BalanceProblemDetail{ProblemDetail: newPD(), Balance: 30, Accounts: []string{"/account/12345"}}
+	expectTrue(t, cmp.Diff(a, b) == "")
+
+	b.Balance = 20
+	expectTrue(t, strings.Contains(cmp.Diff(a, b), "Balance"))
+
+	b.Balance = 30
+	b.ProblemDetail = newPD(problemdetail.WithDetail("Your current balance is 30, but that costs 50."))
+	expectTrue(t, cmp.Diff(a, b) != "")
+}
+
+func TestProblemDetail_EqualIgnoring(t *testing.T) {
+	newPD := func() *problemdetail.ProblemDetail {
+		return problemdetail.New("https://example.com/probs/out-of-credit",
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithExtension("balance", 30),
+			problemdetail.WithDefaults(),
+		)
+	}
+
+	a, b := newPD(), newPD()
+	problemdetail.WithInstanceHash("a")(a)
+	problemdetail.WithExtension("timestamp", 1)(a)
+	problemdetail.WithInstanceHash("b")(b)
+	problemdetail.WithExtension("timestamp", 2)(b)
+
+	expectTrue(t, !a.Equal(b))
+	expectTrue(t, !a.EqualIgnoring(b, "instance"))
+	expectTrue(t, a.EqualIgnoring(b, "instance", "timestamp"))
+
+	problemdetail.WithExtension("balance", 20)(b)
+	expectTrue(t, !a.EqualIgnoring(b, "instance", "timestamp"))
+	expectTrue(t, a.EqualIgnoring(b, "instance", "timestamp", "balance"))
+
+	c := newPD()
+	expectTrue(t, !c.EqualIgnoring(a, "instance"))
+	expectTrue(t, c.EqualIgnoring(a, "instance", "timestamp"))
+	expectTrue(t, !c.EqualIgnoring(nil, "instance"))
+}
+
+func TestWithStrictOptions(t *testing.T) {
+	opts := []problemdetail.Option{
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithInstance("/account/12345/msgs/abc"),
+		problemdetail.WithTitle("Insufficient funds."),
+		problemdetail.WithInstancef("/account/%d", 12345),
+	}
+
+	pd := problemdetail.New("https://example.com/probs/out-of-credit", opts...)
+	expectTrue(t, problemdetail.WriteJSON(httptest.NewRecorder(), pd, 403) == nil)
+	expectTrue(t, pd.Title == "Insufficient funds.")
+
+	pd = problemdetail.New("https://example.com/probs/out-of-credit", append(opts, problemdetail.WithStrictOptions())...)
+	err := problemdetail.WriteJSON(httptest.NewRecorder(), pd, 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrDuplicateOption))
+	expectTrue(t, strings.Contains(err.Error(), "title"))
+	expectTrue(t, strings.Contains(err.Error(), "instance"))
+	expectTrue(t, !strings.Contains(err.Error(), "detail"))
+}
+
+func TestWriteJSON_WithEnvelope(t *testing.T) {
+	data := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithEnvelope("error"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+
+	rawExp := `{"error":{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"balance":30}}`
+	expectTrue(t, rec.Body.String() == rawExp+"\n")
+}
+
+func TestDryRunJSON(t *testing.T) {
+	newPD := func() *problemdetail.ProblemDetail {
+		return problemdetail.New("https://example.com/probs/out-of-credit",
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithDedupKey("out-of-credit-12345"),
+		)
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, newPD(), 403)
+	expectTrue(t, err == nil)
+
+	body, header, err := problemdetail.DryRunJSON(newPD(), 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, string(body) == rec.Body.String())
+	expectTrue(t, reflect.DeepEqual(header, rec.Header()))
+
+	body, header, err = problemdetail.DryRunJSON(problemdetail.New(""), 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, body == nil && header == nil)
+}
+
+func TestWriteJSONIndent(t *testing.T) {
+	data := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithExtension("balance", map[string]any{"current": 30, "cost": 50}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSONIndent(rec, data, 403, "", "  ")
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Code == 403)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+json; charset=utf-8")
+
+	rawExp := `{
+  "type": "https://example.com/probs/out-of-credit",
+  "title": "You do not have enough credit.",
+  "status": 403,
+  "balance": {
+    "cost": 50,
+    "current": 30
+  }
+}
+`
+	expectTrue(t, rec.Body.String() == rawExp)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSONIndent(rec, problemdetail.New(""), 403, "// ", "\t")
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, strings.HasPrefix(err.Error(), "WriteJSONIndent: "))
+}
+
 func expectTrue(t *testing.T, b bool) {
 	t.Helper()
 	if !b {
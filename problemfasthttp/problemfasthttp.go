@@ -0,0 +1,50 @@
+// Package problemfasthttp writes problem details to github.com/valyala/fasthttp responses. It lives in its own module
+// so the core module does not depend on fasthttp.
+package problemfasthttp
+
+import (
+	"net/http"
+
+	"github.com/josestg/problemdetail"
+	"github.com/valyala/fasthttp"
+)
+
+// WriteJSONFastHTTP writes the problem detail to the fasthttp response as JSON. It behaves the same as
+// problemdetail.WriteJSON: the problem detail is validated and encoded the same way, and the status code, the content
+// type and the headers set by the options are written to the response.
+//
+// If the problem detail is invalid, an error is returned and the response is left unchanged.
+func WriteJSONFastHTTP(ctx *fasthttp.RequestCtx, pd problemdetail.ProblemDetailer, status int) error {
+	return problemdetail.WriteJSON(&responseWriter{ctx: ctx, header: make(http.Header)}, pd, status)
+}
+
+// responseWriter adapts fasthttp.RequestCtx to http.ResponseWriter.
+type responseWriter struct {
+	ctx         *fasthttp.RequestCtx
+	header      http.Header
+	wroteHeader bool
+}
+
+// Header implements http.ResponseWriter.
+func (w *responseWriter) Header() http.Header { return w.header }
+
+// WriteHeader implements http.ResponseWriter, it copies the header and the status code to the fasthttp response.
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	for name, values := range w.header {
+		w.ctx.Response.Header.Del(name)
+		for _, value := range values {
+			w.ctx.Response.Header.Add(name, value)
+		}
+	}
+	w.ctx.SetStatusCode(code)
+}
+
+// Write implements http.ResponseWriter, it appends b to the fasthttp response body.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.ctx.Write(b)
+}
@@ -0,0 +1,44 @@
+package problemfasthttp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+	"github.com/josestg/problemdetail/problemfasthttp"
+	"github.com/valyala/fasthttp"
+)
+
+func TestWriteJSONFastHTTP(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithCORSAllowOrigin("https://app.example.com"),
+	)
+
+	var ctx fasthttp.RequestCtx
+	err := problemfasthttp.WriteJSONFastHTTP(&ctx, data, 403)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"about:blank","title":"Forbidden","status":403}`
+	gotRaw := strings.TrimSpace(string(ctx.Response.Body()))
+
+	expectTrue(t, gotRaw == expRaw)
+	expectTrue(t, ctx.Response.StatusCode() == 403)
+	expectTrue(t, string(ctx.Response.Header.ContentType()) == "application/problem+json; charset=utf-8")
+	expectTrue(t, string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")) == "https://app.example.com")
+}
+
+func TestWriteJSONFastHTTP_WithInvalidProblem(t *testing.T) {
+	var ctx fasthttp.RequestCtx
+	err := problemfasthttp.WriteJSONFastHTTP(&ctx, problemdetail.New(""), 0)
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, len(ctx.Response.Body()) == 0)
+}
+
+func expectTrue(t *testing.T, b bool) {
+	t.Helper()
+	if !b {
+		t.Fatal("expected true, got false")
+	}
+}
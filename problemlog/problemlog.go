@@ -0,0 +1,41 @@
+// Package problemlog extracts the members of a problem detail as logging fields. It does not depend on any logger: the
+// key-value slice it produces can be passed to github.com/go-logr/logr, log/slog or any other logger that accepts
+// alternating keys and values.
+package problemlog
+
+import (
+	"sort"
+
+	"github.com/josestg/problemdetail"
+)
+
+// KeysAndValues returns the members of the problem detail as a flat slice of alternating keys and values, suitable for
+// logr.Logger.Info(msg, kv...). The standard members come first, in the order defined by RFC 7807, followed by the
+// extension members sorted by name. The detail and the instance are omitted when they are empty.
+//
+// A nil problem detail yields a nil slice.
+func KeysAndValues(pd *problemdetail.ProblemDetail) []any {
+	if pd == nil {
+		return nil
+	}
+
+	ext := pd.Extensions()
+	kv := make([]any, 0, 2*(5+len(ext)))
+	kv = append(kv, "type", pd.Type, "title", pd.Title, "status", pd.Status)
+	if pd.Detail != "" {
+		kv = append(kv, "detail", pd.Detail)
+	}
+	if pd.Instance != "" {
+		kv = append(kv, "instance", pd.Instance)
+	}
+
+	names := make([]string, 0, len(ext))
+	for name := range ext {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kv = append(kv, name, ext[name])
+	}
+	return kv
+}
@@ -0,0 +1,45 @@
+package problemlog_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+	"github.com/josestg/problemdetail/problemlog"
+)
+
+func TestKeysAndValues(t *testing.T) {
+	pd := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithExtension("accounts", []string{"/account/12345"}),
+	)
+	pd.Status = 403
+
+	exp := []any{
+		"type", "https://example.com/probs/out-of-credit",
+		"title", "You do not have enough credit.",
+		"status", 403,
+		"detail", "Your current balance is 30, but that costs 50.",
+		"accounts", []string{"/account/12345"},
+		"balance", 30,
+	}
+	expectTrue(t, reflect.DeepEqual(problemlog.KeysAndValues(pd), exp))
+}
+
+func TestKeysAndValues_OmitsEmptyOptionalMembers(t *testing.T) {
+	pd := problemdetail.New(problemdetail.Untyped, problemdetail.WithTitle("Not Found"))
+	pd.Status = 404
+
+	exp := []any{"type", problemdetail.Untyped, "title", "Not Found", "status", 404}
+	expectTrue(t, reflect.DeepEqual(problemlog.KeysAndValues(pd), exp))
+	expectTrue(t, problemlog.KeysAndValues(nil) == nil)
+}
+
+func expectTrue(t *testing.T, b bool) {
+	t.Helper()
+	if !b {
+		t.Fatal("expected true, got false")
+	}
+}
@@ -0,0 +1,121 @@
+package problemdetail
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// fieldProblem is a problem of a single field, it is written as an entry of the errors extension member.
+type fieldProblem struct {
+	// Detail is a human-readable explanation of the problem with the field.
+	Detail string `json:"detail" xml:"detail"`
+
+	// Pointer is a JSON Pointer [RFC6901] to the field within the request body.
+	Pointer string `json:"pointer" xml:"pointer"`
+}
+
+// Problems collects field-level problems, for example the result of an input validation, to be written as a single
+// problem detail. The zero value is ready to use.
+type Problems struct {
+	fields []fieldProblem
+}
+
+// Add adds a problem for the given field. The field is the name of a top-level member of the request body, or a JSON
+// Pointer [RFC6901] when it starts with "/".
+func (ps *Problems) Add(field, detail string) {
+	ps.fields = append(ps.fields, fieldProblem{Detail: detail, Pointer: fieldPointer(field)})
+}
+
+// Len returns the number of collected field problems.
+func (ps *Problems) Len() int { return len(ps.fields) }
+
+// AsProblemDetail returns an untyped problem detail with the given status, the collected field problems are written
+// as the errors extension member, each of them with a detail and a pointer to the field.
+func (ps *Problems) AsProblemDetail(status int) *ProblemDetail {
+	fields := make([]fieldProblem, len(ps.fields))
+	copy(fields, ps.fields)
+
+	pd := New(Untyped,
+		WithValidateLevel(LStandard),
+		WithExtension("errors", fields),
+	)
+	pd.WriteStatus(status)
+	return pd
+}
+
+// ValidationProblem is a fluent builder of a validation problem, a problem detail that lists the invalid fields of a
+// request in the errors extension member, the same as Problems.AsProblemDetail.
+type ValidationProblem struct {
+	status   int
+	problems Problems
+}
+
+// NewValidationProblem returns a builder of a validation problem with the given status. If status is 0, it defaults
+// to 422 (Unprocessable Entity).
+func NewValidationProblem(status int) *ValidationProblem {
+	if status == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	return &ValidationProblem{status: status}
+}
+
+// Field adds the reason why the named field is invalid, see Problems.Add for the accepted field names. It returns the
+// builder itself, so calls can be chained.
+func (v *ValidationProblem) Field(name, reason string) *ValidationProblem {
+	v.problems.Add(name, reason)
+	return v
+}
+
+// ProblemDetail returns the validation problem as a problem detail.
+func (v *ValidationProblem) ProblemDetail() *ProblemDetail {
+	return v.problems.AsProblemDetail(v.status)
+}
+
+// pointerEscaper escapes the reference tokens of a JSON Pointer.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc6901#section-3
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// fieldPointer returns the JSON Pointer of the given field.
+func fieldPointer(field string) string {
+	if strings.HasPrefix(field, "/") {
+		return field
+	}
+	return "/" + pointerEscaper.Replace(field)
+}
+
+// WithPointer adds the pointer extension member, a JSON Pointer [RFC6901] to the field that caused the problem. So
+// clients can highlight the offending field, for example "/address/city". An invalid pointer is reported as
+// ErrPointerFormat when the problem detail is validated.
+func WithPointer(ptr string) Option {
+	return func(pd *ProblemDetail) {
+		if !isPointer(ptr) {
+			pd.errs = append(pd.errs, fmt.Errorf("%w: %q", ErrPointerFormat, ptr))
+			return
+		}
+		WithExtension("pointer", ptr)(pd)
+	}
+}
+
+// isPointer reports whether ptr is a valid JSON Pointer: either empty, or a sequence of "/" prefixed reference tokens
+// where "~" is only used in the escape sequences "~0" and "~1".
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc6901#section-3
+func isPointer(ptr string) bool {
+	if ptr == "" {
+		return true
+	}
+	if ptr[0] != '/' {
+		return false
+	}
+	for i := 0; i < len(ptr); i++ {
+		if ptr[i] != '~' {
+			continue
+		}
+		if i+1 == len(ptr) || (ptr[i+1] != '0' && ptr[i+1] != '1') {
+			return false
+		}
+	}
+	return true
+}
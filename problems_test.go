@@ -0,0 +1,77 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestProblems_AsProblemDetail(t *testing.T) {
+	var problems problemdetail.Problems
+	problems.Add("age", "must be a positive integer")
+	problems.Add("a/b", "must not be empty")
+	problems.Add("/address/city", "must be a known city")
+	expectTrue(t, problems.Len() == 3)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, problems.AsProblemDetail(422), 422)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"about:blank","title":"Unprocessable Entity","status":422,"errors":[{"detail":"must be a positive integer","pointer":"/age"},{"detail":"must not be empty","pointer":"/a~1b"},{"detail":"must be a known city","pointer":"/address/city"}]}`
+	gotRaw := strings.TrimSpace(rec.Body.String())
+
+	expectTrue(t, gotRaw == expRaw)
+	expectTrue(t, rec.Code == 422)
+}
+
+func TestWithPointer(t *testing.T) {
+	tests := []struct {
+		ptr string
+		ok  bool
+	}{
+		{ptr: "", ok: true},
+		{ptr: "/age", ok: true},
+		{ptr: "/address/city", ok: true},
+		{ptr: "/a~1b/m~0n", ok: true},
+		{ptr: "age", ok: false},
+		{ptr: "/a~2b", ok: false},
+		{ptr: "/a~", ok: false},
+	}
+
+	for _, tt := range tests {
+		pd := problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithPointer(tt.ptr),
+		)
+		pd.WriteStatus(422)
+
+		err := pd.Validate()
+		expectTrue(t, errors.Is(err, problemdetail.ErrPointerFormat) == !tt.ok)
+		if tt.ok {
+			expectTrue(t, pd.Extensions()["pointer"] == tt.ptr)
+		}
+	}
+}
+
+func TestNewValidationProblem(t *testing.T) {
+	pd := problemdetail.NewValidationProblem(0).
+		Field("email", "must be a valid email address").
+		Field("age", "must be a positive integer").
+		ProblemDetail()
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, pd, pd.Status)
+	expectTrue(t, err == nil)
+
+	expRaw := `{"type":"about:blank","title":"Unprocessable Entity","status":422,"errors":[{"detail":"must be a valid email address","pointer":"/email"},{"detail":"must be a positive integer","pointer":"/age"}]}`
+	gotRaw := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, gotRaw == expRaw)
+	expectTrue(t, rec.Code == 422)
+
+	pd = problemdetail.NewValidationProblem(400).Field("name", "is required").ProblemDetail()
+	expectTrue(t, pd.Status == 400)
+	expectTrue(t, pd.Title == "Bad Request")
+}
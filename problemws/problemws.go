@@ -0,0 +1,34 @@
+// Package problemws sends problem details over WebSocket connections, so a realtime API reports its errors the same
+// way as its HTTP endpoints. It does not depend on any WebSocket library: *websocket.Conn of
+// github.com/gorilla/websocket implements Conn as it is, and other libraries can be adapted with a small wrapper.
+package problemws
+
+import (
+	"bytes"
+
+	"github.com/josestg/problemdetail"
+)
+
+// TextMessage is the message type of a text data frame, the same as websocket.TextMessage of gorilla/websocket.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc6455#section-11.8
+const TextMessage = 1
+
+// Conn is the part of a WebSocket connection used by WriteMessage. For a library with a context-based API, such as
+// github.com/coder/websocket, a wrapper can call conn.Write(ctx, websocket.MessageText, data).
+type Conn interface {
+	WriteMessage(messageType int, data []byte) error
+}
+
+// WriteMessage sends the JSON encoding of the problem detail as a text message. The problem detail is validated and
+// encoded as problemdetail.EncodeJSON does, without the trailing newline, and ProblemDetail.Status is kept as it is,
+// since a WebSocket message has no status code.
+//
+// If the problem detail is invalid, an error is returned and nothing is sent.
+func WriteMessage(conn Conn, pd problemdetail.ProblemDetailer) error {
+	var buf bytes.Buffer
+	if err := problemdetail.EncodeJSON(&buf, pd); err != nil {
+		return err
+	}
+	return conn.WriteMessage(TextMessage, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
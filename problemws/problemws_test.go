@@ -0,0 +1,46 @@
+package problemws_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+	"github.com/josestg/problemdetail/problemws"
+)
+
+// recordingConn records the messages sent by WriteMessage.
+type recordingConn struct {
+	types    []int
+	messages []string
+}
+
+func (c *recordingConn) WriteMessage(messageType int, data []byte) error {
+	c.types = append(c.types, messageType)
+	c.messages = append(c.messages, string(data))
+	return nil
+}
+
+func TestWriteMessage(t *testing.T) {
+	pd := problemdetail.Errorf("https://example.com/probs/out-of-credit", 403, "balance is %d", 30)
+
+	var conn recordingConn
+	err := problemws.WriteMessage(&conn, pd)
+	expectTrue(t, err == nil)
+	expectTrue(t, len(conn.messages) == 1)
+	expectTrue(t, conn.types[0] == problemws.TextMessage)
+	expectTrue(t, conn.messages[0] == `{"type":"https://example.com/probs/out-of-credit","title":"Forbidden","status":403,"detail":"balance is 30"}`)
+}
+
+func TestWriteMessage_WithInvalidProblem(t *testing.T) {
+	var conn recordingConn
+	err := problemws.WriteMessage(&conn, problemdetail.New(""))
+	expectTrue(t, errors.Is(err, problemdetail.ErrTypeRequired))
+	expectTrue(t, len(conn.messages) == 0)
+}
+
+func expectTrue(t *testing.T, b bool) {
+	t.Helper()
+	if !b {
+		t.Fatal("expected true, got false")
+	}
+}
@@ -0,0 +1,83 @@
+package problemdetail
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRetryAfter is the delay set by SetDefaultRetryAfter, in seconds, the zero value means a minute.
+var defaultRetryAfter atomic.Int64
+
+// SetDefaultRetryAfter sets the delay of the Retry-After header that WithRetriable sets when no other option sets it.
+// It is rounded up to whole seconds. A non-positive delay restores the default, a minute. It is safe for concurrent
+// use, but it is meant to be set once at program start.
+func SetDefaultRetryAfter(d time.Duration) {
+	if d <= 0 {
+		defaultRetryAfter.Store(0)
+		return
+	}
+	defaultRetryAfter.Store(int64(math.Ceil(d.Seconds())))
+}
+
+// WithRetriable adds the retriable extension member, which tells the clients whether retrying the request may
+// succeed, so they can choose between a backoff and a fail-fast. When it is true and the status code is 503 (Service
+// Unavailable) or 429 (Too Many Requests), the writers also set the Retry-After header, unless it is set by another
+// option such as WithQuota, to the delay set by SetDefaultRetryAfter.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9110#section-10.2.3
+func WithRetriable(retriable bool) Option {
+	return func(pd *ProblemDetail) {
+		WithExtension("retriable", retriable)(pd)
+		if !retriable {
+			return
+		}
+		pd.headers = append(pd.headers, func(h http.Header, p *ProblemDetail) {
+			if p.Status != http.StatusServiceUnavailable && p.Status != http.StatusTooManyRequests {
+				return
+			}
+			if h.Get("Retry-After") != "" {
+				return
+			}
+			delay := defaultRetryAfter.Load()
+			if delay == 0 {
+				delay = 60
+			}
+			h.Set("Retry-After", strconv.FormatInt(delay, 10))
+		})
+	}
+}
+
+// WithQuota adds the limit, remaining and reset extension members that describe the exhausted quota of a
+// rate-limited request, typically with status 429 (Too Many Requests).
+//
+// The writers also set the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers as described by the IETF
+// RateLimit header fields draft, and the Retry-After header. Both reset and retry values are the number of seconds
+// until reset, computed at write time.
+//
+// ref: https://datatracker.ietf.org/doc/html/draft-ietf-httpapi-ratelimit-headers
+func WithQuota(limit, remaining int, reset time.Time) Option {
+	return func(pd *ProblemDetail) {
+		WithExtension("limit", limit)(pd)
+		WithExtension("remaining", remaining)(pd)
+		WithExtension("reset", reset)(pd)
+		pd.headers = append(pd.headers, func(h http.Header, _ *ProblemDetail) {
+			delay := strconv.FormatInt(secondsUntil(reset), 10)
+			h.Set("RateLimit-Limit", strconv.Itoa(limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			h.Set("RateLimit-Reset", delay)
+			h.Set("Retry-After", delay)
+		})
+	}
+}
+
+// secondsUntil returns the number of whole seconds until t, rounded up. It never returns a negative value.
+func secondsUntil(t time.Time) int64 {
+	d := time.Until(t)
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
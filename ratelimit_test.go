@@ -0,0 +1,102 @@
+package problemdetail_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSON_WithQuota(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second)
+	data := problemdetail.New(
+		problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithQuota(100, 0, reset),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 429)
+	expectTrue(t, err == nil)
+
+	expectTrue(t, rec.Code == 429)
+	expectTrue(t, rec.Header().Get("RateLimit-Limit") == "100")
+	expectTrue(t, rec.Header().Get("RateLimit-Remaining") == "0")
+	expectTrue(t, rec.Header().Get("RateLimit-Reset") == "30")
+	expectTrue(t, rec.Header().Get("Retry-After") == "30")
+
+	resetRaw, _ := reset.MarshalJSON()
+	expRaw := `{"type":"about:blank","title":"Too Many Requests","status":429,"limit":100,"remaining":0,"reset":` + string(resetRaw) + `}`
+	gotRaw := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, gotRaw == expRaw)
+}
+
+func TestWriteJSON_WithQuotaAlreadyReset(t *testing.T) {
+	data := problemdetail.New(
+		problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithQuota(100, 0, time.Now().Add(-time.Minute)),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 429)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("RateLimit-Reset") == "0")
+	expectTrue(t, rec.Header().Get("Retry-After") == "0")
+}
+
+func TestWriteJSON_WithRetriable(t *testing.T) {
+	tests := []struct {
+		retriable  bool
+		status     int
+		retryAfter string
+	}{
+		{retriable: true, status: 503, retryAfter: "60"},
+		{retriable: true, status: 429, retryAfter: "60"},
+		{retriable: true, status: 500, retryAfter: ""},
+		{retriable: false, status: 503, retryAfter: ""},
+	}
+
+	for _, tt := range tests {
+		data := problemdetail.New(
+			problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithRetriable(tt.retriable),
+		)
+
+		rec := httptest.NewRecorder()
+		err := problemdetail.WriteJSON(rec, data, tt.status)
+		expectTrue(t, err == nil)
+		expectTrue(t, rec.Header().Get("Retry-After") == tt.retryAfter)
+		expectTrue(t, strings.HasSuffix(strings.TrimSpace(rec.Body.String()), fmt.Sprintf(`"retriable":%t}`, tt.retriable)))
+	}
+}
+
+func TestWriteJSON_WithRetriableDelay(t *testing.T) {
+	problemdetail.SetDefaultRetryAfter(1500 * time.Millisecond)
+	t.Cleanup(func() { problemdetail.SetDefaultRetryAfter(0) })
+
+	data := problemdetail.New(
+		problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithRetriable(true),
+	)
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 503)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Retry-After") == "2")
+
+	data = problemdetail.New(
+		problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithRetriable(true),
+		problemdetail.WithQuota(100, 0, time.Now().Add(30*time.Second)),
+	)
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 429)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Retry-After") == "30")
+}
@@ -0,0 +1,342 @@
+package problemdetail
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// standardMembers is the set of members defined by RFC 7807, any other member is an extension member.
+var standardMembers = map[string]struct{}{
+	"type":     {},
+	"title":    {},
+	"status":   {},
+	"detail":   {},
+	"instance": {},
+}
+
+// ReadFrom implements io.ReaderFrom. It decodes a JSON problem detail from r into the receiver, the receiver is reset
+// by Reset before decoding so it can be reused, for example from a sync.Pool. Members that are not defined by RFC 7807
+// are kept as extension members. It returns the number of bytes read from r.
+func (p *ProblemDetail) ReadFrom(r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	n := int64(len(data))
+	if err != nil {
+		return n, err
+	}
+
+	p.Reset()
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return n, err
+	}
+
+	// decode through a type without methods, so the standard members are decoded by their struct tags.
+	type standard ProblemDetail
+	if err := json.Unmarshal(data, (*standard)(p)); err != nil {
+		return n, err
+	}
+
+	for name, raw := range members {
+		if _, ok := standardMembers[name]; ok {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return n, err
+		}
+		WithExtension(name, value)(p)
+	}
+	return n, nil
+}
+
+// ParseJSON decodes a JSON problem detail from r into a new T, a struct that embeds *ProblemDetail, such as:
+//
+//	type BalanceProblemDetail struct {
+//		*problemdetail.ProblemDetail
+//		Balance int32 `json:"balance"`
+//	}
+//
+// The members that are fields of T are decoded into the fields, with their types, and the other members into the
+// embedded ProblemDetail as ReadFrom does, so the members that T does not know are kept as extension members. It
+// complements ReadFrom for the problem types whose shape is known by the client.
+//
+// If T is not a struct embedding *ProblemDetail, ErrParseTarget is returned.
+func ParseJSON[T any](r io.Reader) (*T, error) {
+	v := new(T)
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ParseJSON: %w: %T", ErrParseTarget, *v)
+	}
+	embedded, ok := rv.Type().FieldByName("ProblemDetail")
+	if !ok || !embedded.Anonymous || embedded.Type != reflect.TypeOf((*ProblemDetail)(nil)) {
+		return nil, fmt.Errorf("ParseJSON: %w: %T", ErrParseTarget, *v)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ParseJSON: %w", err)
+	}
+	pd := newPlain("")
+	if _, err := pd.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("ParseJSON: %w", err)
+	}
+	rv.FieldByIndex(embedded.Index).Set(reflect.ValueOf(pd))
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("ParseJSON: %w", err)
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		if name, ok := jsonFieldName(rv.Type().Field(i)); ok {
+			delete(pd.extensions, name)
+		}
+	}
+	return v, nil
+}
+
+// jsonFieldName returns the member name of the struct field as encoding/json names it, it returns false if the field
+// is not encoded as a member of its own.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() || field.Anonymous {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// ReadResponse decodes the JSON problem detail from the body of resp. If the body has no status member, the status
+// code of resp is used. The Retry-After header of resp is kept, so it is available from ProblemDetail.RetryAfter.
+// The caller is responsible for closing the body.
+func ReadResponse(resp *http.Response) (*ProblemDetail, error) {
+	pd := newPlain("")
+	if _, err := pd.ReadFrom(resp.Body); err != nil {
+		return nil, fmt.Errorf("ReadResponse: %w", err)
+	}
+	if pd.Status == 0 {
+		pd.Status = resp.StatusCode
+	}
+	pd.retryAfter = resp.Header.Get("Retry-After")
+	return pd, nil
+}
+
+// FromHTTPResponse returns the problem detail carried by resp, typically the response of an upstream service in a
+// gateway. If the body is an application/problem+json or application/problem+xml problem detail, it is decoded as
+// ReadResponse and ReadXML do. Otherwise, for example a bare 502 without body, an untyped problem detail is built from
+// the status code of resp, with the upstream extension member set to that status code, so the gateway errors reach
+// the clients in a standard form. The validation level of the built problem detail is LStandard.
+//
+// The body is consumed, the caller is responsible for closing it.
+func FromHTTPResponse(resp *http.Response) *ProblemDetail {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/problem+json":
+		if pd, err := ReadResponse(resp); err == nil {
+			return pd
+		}
+	case "application/problem+xml":
+		if pd, _, err := ReadXML(resp.Body); err == nil {
+			if pd.Status == 0 {
+				pd.Status = resp.StatusCode
+			}
+			pd.retryAfter = resp.Header.Get("Retry-After")
+			return pd
+		}
+	}
+
+	pd := New(Untyped, WithValidateLevel(LStandard), WithExtension("upstream", resp.StatusCode))
+	pd.WriteStatus(resp.StatusCode)
+	pd.retryAfter = resp.Header.Get("Retry-After")
+	return pd
+}
+
+// WithStatusFrom sets the status of the ProblemDetail to the status code of resp, typically the response of an upstream
+// service, and derives the title from it if the problem detail has no title, see SetStatusTitleFunc. So a gateway
+// builds a problem detail that mirrors an upstream failure in one call. A nil response is a no-op.
+func WithStatusFrom(resp *http.Response) Option {
+	return func(pd *ProblemDetail) {
+		if resp == nil {
+			return
+		}
+		pd.WriteStatus(resp.StatusCode)
+		if pd.Title == "" {
+			pd.Title = statusTitle(resp.StatusCode)
+		}
+	}
+}
+
+// RetryAfter returns how long the client should wait before retrying, according to the Retry-After header of the
+// response read by ReadResponse. Both delta-seconds and HTTP-date forms are supported, a date in the past means no
+// wait. It returns false if the header is absent or invalid.
+//
+// ref: https://datatracker.ietf.org/doc/html/rfc9110#section-10.2.3
+func (p *ProblemDetail) RetryAfter() (time.Duration, bool) {
+	if p.retryAfter == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(p.retryAfter, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(p.retryAfter)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(date), 0), true
+}
+
+// xmlNamespace is the XML namespace of the problem detail.
+//
+// ref: https://tools.ietf.org/html/rfc7807#appendix-A
+const xmlNamespace = "urn:ietf:rfc:7807"
+
+// ReadXMLOption customizes ReadXML.
+type ReadXMLOption func(*readXMLConfig)
+
+// readXMLConfig is the configuration of ReadXML.
+type readXMLConfig struct {
+	anyNamespace bool
+}
+
+// WithAnyXMLNamespace makes ReadXML accept a problem element without namespace, or with a namespace other than
+// urn:ietf:rfc:7807.
+func WithAnyXMLNamespace() ReadXMLOption {
+	return func(c *readXMLConfig) { c.anyNamespace = true }
+}
+
+// ReadXML decodes an XML problem detail, whose root is the problem element of the urn:ietf:rfc:7807 namespace. The
+// standard members are decoded into the returned problem detail, and any other child element is collected as an
+// extension member, which are also returned as a map. An element with child elements is decoded as a map, and a
+// repeated element is decoded as a slice, any other element is decoded as its text. The status code is read from the
+// code attribute of the status element if it has one, as written with WithXMLStatusText.
+func ReadXML(r io.Reader, opts ...ReadXMLOption) (*ProblemDetail, map[string]any, error) {
+	var cfg readXMLConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	dec := xml.NewDecoder(r)
+	root, err := nextStartElement(dec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadXML: %w", err)
+	}
+	if root.Name.Local != "problem" {
+		return nil, nil, fmt.Errorf("ReadXML: unexpected root element %q", root.Name.Local)
+	}
+	if !cfg.anyNamespace && root.Name.Space != xmlNamespace {
+		return nil, nil, fmt.Errorf("ReadXML: unexpected namespace %q", root.Name.Space)
+	}
+
+	// the status element written with WithXMLStatusText holds the code as an attribute, and the status text as content.
+	var statusCode string
+	content, err := decodeXMLElement(dec, func(start xml.StartElement) {
+		if start.Name.Local != "status" {
+			return
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "code" {
+				statusCode = attr.Value
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadXML: %w", err)
+	}
+
+	pd := newPlain("")
+	members, _ := content.(map[string]any) // a problem element without child elements has no members.
+	for name, value := range members {
+		text, _ := value.(string)
+		switch name {
+		case "type":
+			pd.Type = text
+		case "title":
+			pd.Title = text
+		case "status":
+			if statusCode != "" {
+				text = statusCode
+			}
+			if pd.Status, err = strconv.Atoi(strings.TrimSpace(text)); err != nil {
+				return nil, nil, fmt.Errorf("ReadXML: status: %w", err)
+			}
+		case "detail":
+			pd.Detail = text
+		case "instance":
+			pd.Instance = text
+		default:
+			WithExtension(name, value)(pd)
+		}
+	}
+	return pd, pd.Extensions(), nil
+}
+
+// nextStartElement returns the next start element, skipping the prolog and comments.
+func nextStartElement(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// decodeXMLElement decodes the content of the current element, up to its end element. It returns the text of the
+// element if it has no child elements, otherwise a map of its child elements, where a repeated element is decoded as
+// a slice. If child is not nil, it is called with the start element of every child element.
+func decodeXMLElement(dec *xml.Decoder, child func(start xml.StartElement)) (any, error) {
+	var text strings.Builder
+	var children map[string]any
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.CharData:
+			text.Write(tok)
+		case xml.StartElement:
+			if child != nil {
+				child(tok)
+			}
+			value, err := decodeXMLElement(dec, nil)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = make(map[string]any)
+			}
+			name := tok.Name.Local
+			switch prev := children[name].(type) {
+			case nil:
+				children[name] = value
+			case []any:
+				children[name] = append(prev, value)
+			default:
+				children[name] = []any{prev, value}
+			}
+		case xml.EndElement:
+			if children == nil {
+				return text.String(), nil
+			}
+			return children, nil
+		}
+	}
+}
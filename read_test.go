@@ -0,0 +1,296 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestProblemDetail_ReadFrom(t *testing.T) {
+	raw := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/abc","balance":30}`
+
+	pd := problemdetail.New("https://example.com/probs/stale", problemdetail.WithExtension("stale", true))
+	n, err := pd.ReadFrom(strings.NewReader(raw))
+	expectTrue(t, err == nil)
+	expectTrue(t, n == int64(len(raw)))
+
+	expectTrue(t, pd.Type == "https://example.com/probs/out-of-credit")
+	expectTrue(t, pd.Title == "You do not have enough credit.")
+	expectTrue(t, pd.Status == 403)
+	expectTrue(t, pd.Detail == "Your current balance is 30, but that costs 50.")
+	expectTrue(t, pd.Instance == "/account/12345/abc")
+
+	ext := pd.Extensions()
+	expectTrue(t, len(ext) == 1)
+	expectTrue(t, ext["balance"] == float64(30))
+	expectTrue(t, pd.Validate() == nil)
+}
+
+func TestProblemDetail_ReadFromInvalidJSON(t *testing.T) {
+	pd := problemdetail.New(problemdetail.Untyped)
+	_, err := pd.ReadFrom(strings.NewReader(`{"type":`))
+	expectTrue(t, err != nil)
+}
+
+func TestParseJSON(t *testing.T) {
+	raw := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"balance":30,"accounts":["/account/12345"],"traceId":"abc"}`
+
+	data, err := problemdetail.ParseJSON[BalanceProblemDetail](strings.NewReader(raw))
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Type == "https://example.com/probs/out-of-credit")
+	expectTrue(t, data.Status == 403)
+	expectTrue(t, data.Balance == 30)
+	expectTrue(t, len(data.Accounts) == 1 && data.Accounts[0] == "/account/12345")
+
+	ext := data.Extensions()
+	expectTrue(t, len(ext) == 1 && ext["traceId"] == "abc")
+
+	problemdetail.WithValidateLevel(problemdetail.LStandard)(data.ProblemDetail)
+	rec := httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expRaw := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"balance":30,"accounts":["/account/12345"],"traceId":"abc"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+}
+
+func TestParseJSON_InvalidTarget(t *testing.T) {
+	_, err := problemdetail.ParseJSON[struct{ Balance int }](strings.NewReader(`{}`))
+	expectTrue(t, errors.Is(err, problemdetail.ErrParseTarget))
+
+	_, err = problemdetail.ParseJSON[string](strings.NewReader(`{}`))
+	expectTrue(t, errors.Is(err, problemdetail.ErrParseTarget))
+
+	_, err = problemdetail.ParseJSON[BalanceProblemDetail](strings.NewReader(`{"balance":"thirty"}`))
+	expectTrue(t, err != nil)
+}
+
+func TestReadResponse(t *testing.T) {
+	raw := `{"type":"about:blank","title":"Too Many Requests","detail":"Slow down."}`
+	resp := &http.Response{
+		StatusCode: 429,
+		Header:     http.Header{"Retry-After": []string{"120"}},
+		Body:       io.NopCloser(strings.NewReader(raw)),
+	}
+
+	pd, err := problemdetail.ReadResponse(resp)
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Status == 429)
+	expectTrue(t, pd.Detail == "Slow down.")
+
+	d, ok := pd.RetryAfter()
+	expectTrue(t, ok)
+	expectTrue(t, d == 120*time.Second)
+}
+
+func TestFromHTTPResponse(t *testing.T) {
+	raw := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403}`
+	resp := &http.Response{
+		StatusCode: 403,
+		Header:     http.Header{"Content-Type": []string{"application/problem+json; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(raw)),
+	}
+
+	pd := problemdetail.FromHTTPResponse(resp)
+	expectTrue(t, pd.Type == "https://example.com/probs/out-of-credit")
+	expectTrue(t, pd.Status == 403)
+	expectTrue(t, len(pd.Extensions()) == 0)
+
+	raw = `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Not Found</title></problem>`
+	resp = &http.Response{
+		StatusCode: 404,
+		Header:     http.Header{"Content-Type": []string{"application/problem+xml"}},
+		Body:       io.NopCloser(strings.NewReader(raw)),
+	}
+
+	pd = problemdetail.FromHTTPResponse(resp)
+	expectTrue(t, pd.Title == "Not Found")
+	expectTrue(t, pd.Status == 404)
+}
+
+func TestFromHTTPResponse_NotProblem(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 502,
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Body:       io.NopCloser(strings.NewReader("<h1>Bad Gateway</h1>")),
+	}
+
+	pd := problemdetail.FromHTTPResponse(resp)
+	expectTrue(t, pd.Type == problemdetail.Untyped)
+	expectTrue(t, pd.Title == "Bad Gateway")
+	expectTrue(t, pd.Status == 502)
+	expectTrue(t, pd.Extensions()["upstream"] == 502)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, pd, 502)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Bad Gateway","status":502,"upstream":502}`)
+
+	resp = &http.Response{
+		StatusCode: 503,
+		Header:     http.Header{"Content-Type": []string{"application/problem+json"}},
+		Body:       io.NopCloser(strings.NewReader("upstream timeout")),
+	}
+	pd = problemdetail.FromHTTPResponse(resp)
+	expectTrue(t, pd.Status == 503)
+	expectTrue(t, pd.Extensions()["upstream"] == 503)
+}
+
+func TestWithStatusFrom(t *testing.T) {
+	resp := &http.Response{StatusCode: 504}
+
+	pd := problemdetail.New("https://example.com/probs/upstream-timeout", problemdetail.WithStatusFrom(resp))
+	expectTrue(t, pd.Status == 504)
+	expectTrue(t, pd.Title == "Gateway Timeout")
+
+	pd = problemdetail.New("https://example.com/probs/upstream-timeout",
+		problemdetail.WithTitle("The upstream service timed out."),
+		problemdetail.WithStatusFrom(resp),
+	)
+	expectTrue(t, pd.Status == 504)
+	expectTrue(t, pd.Title == "The upstream service timed out.")
+
+	pd = problemdetail.New(problemdetail.Untyped, problemdetail.WithStatusFrom(nil))
+	expectTrue(t, pd.Status == 0)
+	expectTrue(t, pd.Title == "")
+}
+
+func TestProblemDetail_RetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{header: "", ok: false},
+		{header: "30", ok: true},
+		{header: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), ok: true},
+		{header: "soon", ok: false},
+		{header: "-1", ok: false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{
+			StatusCode: 503,
+			Header:     http.Header{"Retry-After": []string{tt.header}},
+			Body:       io.NopCloser(strings.NewReader(`{"type":"about:blank"}`)),
+		}
+		pd, err := problemdetail.ReadResponse(resp)
+		expectTrue(t, err == nil)
+
+		d, ok := pd.RetryAfter()
+		expectTrue(t, ok == tt.ok)
+		expectTrue(t, d >= 0 && d <= time.Hour)
+	}
+}
+
+func TestReadXML(t *testing.T) {
+	raw := `<?xml version="1.0" encoding="UTF-8"?>
+<problem xmlns="urn:ietf:rfc:7807">
+  <type>https://example.com/probs/out-of-credit</type>
+  <title>You do not have enough credit.</title>
+  <status>403</status>
+  <detail>Your current balance is 30, but that costs 50.</detail>
+  <instance>/account/12345/abc</instance>
+  <balance>30</balance>
+  <accounts>/account/12345</accounts>
+  <accounts>/account/67890</accounts>
+  <owner><name>John</name></owner>
+</problem>`
+
+	pd, ext, err := problemdetail.ReadXML(strings.NewReader(raw))
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Type == "https://example.com/probs/out-of-credit")
+	expectTrue(t, pd.Title == "You do not have enough credit.")
+	expectTrue(t, pd.Status == 403)
+	expectTrue(t, pd.Detail == "Your current balance is 30, but that costs 50.")
+	expectTrue(t, pd.Instance == "/account/12345/abc")
+	expectTrue(t, pd.Validate() == nil)
+
+	expectTrue(t, len(ext) == 3)
+	expectTrue(t, ext["balance"] == "30")
+	accounts, ok := ext["accounts"].([]any)
+	expectTrue(t, ok && len(accounts) == 2 && accounts[0] == "/account/12345" && accounts[1] == "/account/67890")
+	owner, ok := ext["owner"].(map[string]any)
+	expectTrue(t, ok && owner["name"] == "John")
+}
+
+func TestReadXML_WithNamespace(t *testing.T) {
+	raw := `<problem><type>about:blank</type><status>404</status></problem>`
+
+	_, _, err := problemdetail.ReadXML(strings.NewReader(raw))
+	expectTrue(t, err != nil)
+
+	pd, ext, err := problemdetail.ReadXML(strings.NewReader(raw), problemdetail.WithAnyXMLNamespace())
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Type == problemdetail.Untyped)
+	expectTrue(t, pd.Status == 404)
+	expectTrue(t, len(ext) == 0)
+
+	_, _, err = problemdetail.ReadXML(strings.NewReader(`<error xmlns="urn:ietf:rfc:7807"/>`))
+	expectTrue(t, err != nil)
+}
+
+func TestReadXML_RoundTrip(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithXMLNamespacePrefix("p"),
+		problemdetail.WithExtension("balance", 30),
+	)
+	rec := httptest.NewRecorder()
+	expectTrue(t, problemdetail.WriteXML(rec, data, 403) == nil)
+
+	pd, ext, err := problemdetail.ReadXML(rec.Body)
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Title == "Forbidden")
+	expectTrue(t, ext["balance"] == "30")
+}
+
+func TestReadXML_WithoutDefaultOptions(t *testing.T) {
+	problemdetail.SetDefaultOptions(
+		problemdetail.WithExtension("service", "billing"),
+		problemdetail.WithTitle("Something went wrong."),
+	)
+	t.Cleanup(func() { problemdetail.SetDefaultOptions() })
+
+	raw := `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><status>404</status></problem>`
+	pd, _, err := problemdetail.ReadXML(strings.NewReader(raw))
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Title == "")
+	expectTrue(t, len(pd.Extensions()) == 0)
+
+	pd = problemdetail.FromHTTPResponse(&http.Response{
+		StatusCode: 404,
+		Header:     http.Header{"Content-Type": []string{"application/problem+xml"}},
+		Body:       io.NopCloser(strings.NewReader(raw)),
+	})
+	expectTrue(t, pd.Title == "")
+	expectTrue(t, len(pd.Extensions()) == 0)
+
+	pd, err = problemdetail.ReadResponse(&http.Response{
+		StatusCode: 404,
+		Body:       io.NopCloser(strings.NewReader(`{"type":"about:blank","status":404}`)),
+	})
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Title == "")
+	expectTrue(t, len(pd.Extensions()) == 0)
+}
+
+func TestReadXML_StatusText(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithXMLStatusText(),
+	)
+	rec := httptest.NewRecorder()
+	expectTrue(t, problemdetail.WriteXML(rec, data, 403) == nil)
+	expectTrue(t, strings.Contains(rec.Body.String(), `<status code="403">Forbidden</status>`))
+
+	pd, ext, err := problemdetail.ReadXML(rec.Body)
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Status == 403)
+	expectTrue(t, pd.Title == "Forbidden")
+	expectTrue(t, len(ext) == 0)
+}
@@ -0,0 +1,75 @@
+package problemdetail
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// statusRecorder is an http.ResponseWriter that records whether the response is committed, that is whether its status
+// code is written. It is applied by Middleware, so the writers refuse to write a problem detail in the middle of
+// another response, see AlreadyWritten.
+type statusRecorder struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter. An informational status code, other than 101 (Switching Protocols),
+// does not commit the response.
+func (w *statusRecorder) WriteHeader(code int) {
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter, a body without a status code commits the response with 200 (OK).
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, flushing commits the response. It is a no-op if the underlying response writer does
+// not support flushing.
+func (w *statusRecorder) Flush() {
+	w.wroteHeader = true
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker, a hijacked connection commits the response. It returns an error wrapping
+// http.ErrNotSupported if the underlying response writer does not support hijacking.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying response writer, for http.ResponseController.
+func (w *statusRecorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// AlreadyWritten reports whether the status code of the response is already written, so a middleware or a handler
+// can tell whether a problem detail can still replace the response. It knows only about the responses served through
+// Middleware, including when w wraps such a response and has an Unwrap() http.ResponseWriter method, it reports false
+// for any other response writer. The writers of the package return ErrAlreadyWritten instead of writing a problem
+// detail in the middle of a committed response.
+func AlreadyWritten(w http.ResponseWriter) bool {
+	rec := recorderOf(w)
+	return rec != nil && rec.wroteHeader
+}
+
+// recorderOf returns the statusRecorder that w is or wraps, or nil if there is none.
+func recorderOf(w http.ResponseWriter) *statusRecorder {
+	for w != nil {
+		if rec, ok := w.(*statusRecorder); ok {
+			return rec
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
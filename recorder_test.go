@@ -0,0 +1,70 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+// wrappedWriter is a response writer of a third-party middleware that supports unwrapping.
+type wrappedWriter struct{ http.ResponseWriter }
+
+func (w wrappedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestAlreadyWritten(t *testing.T) {
+	expectTrue(t, !problemdetail.AlreadyWritten(httptest.NewRecorder()))
+
+	var before, informational, after bool
+	var writeErr error
+	handler := problemdetail.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w = wrappedWriter{w}
+		before = problemdetail.AlreadyWritten(w)
+		w.WriteHeader(http.StatusEarlyHints)
+		informational = problemdetail.AlreadyWritten(w)
+		_, _ = w.Write([]byte("partial"))
+		after = problemdetail.AlreadyWritten(w)
+
+		data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+		writeErr = problemdetail.WriteJSON(w, data, 500)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expectTrue(t, !before)
+	expectTrue(t, !informational)
+	expectTrue(t, after)
+	expectTrue(t, errors.Is(writeErr, problemdetail.ErrAlreadyWritten))
+	expectTrue(t, errors.Is(writeErr, problemdetail.ErrIO))
+	expectTrue(t, rec.Body.String() == "partial")
+}
+
+func TestMiddleware_FlusherHijacker(t *testing.T) {
+	var flushErr, hijackErr error
+	var committed bool
+	handler := problemdetail.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			flushErr = errors.New("not a flusher")
+			return
+		}
+		f.Flush()
+		committed = problemdetail.AlreadyWritten(w)
+
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			hijackErr = errors.New("not a hijacker")
+			return
+		}
+		_, _, hijackErr = h.Hijack()
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expectTrue(t, flushErr == nil)
+	expectTrue(t, rec.Flushed)
+	expectTrue(t, committed)
+	expectTrue(t, errors.Is(hijackErr, http.ErrNotSupported))
+}
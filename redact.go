@@ -0,0 +1,56 @@
+package problemdetail
+
+import "maps"
+
+// redacted replaces the value of a redacted member.
+const redacted = "[redacted]"
+
+// Redact returns a copy of the problem detail that is safe to log, where the given members are replaced by
+// "[redacted]". The members are the names of the string standard members, type, title, detail and instance, or of the
+// extension members. Without members, detail and instance are redacted, since they commonly carry personal data and
+// resource ids; type, title and status are kept. A name that is not set is ignored. Redacting detail also redacts the
+// detailTranslations extension member of WithDetailTranslations, which holds the detail in every language, and
+// redacting instance also redacts the path extension member of WithRequest, which holds the same request path.
+//
+// The problem detail itself is left unchanged, so it can still be written to the client.
+func (p *ProblemDetail) Redact(members ...string) *ProblemDetail {
+	if len(members) == 0 {
+		members = []string{"detail", "instance"}
+	}
+
+	clone := *p
+	clone.extensions = maps.Clone(p.extensions)
+	for _, name := range members {
+		switch name {
+		case "type":
+			redact(&clone.Type)
+		case "title":
+			redact(&clone.Title)
+		case "detail":
+			redact(&clone.Detail)
+			clone.detailTemplate = ""
+			clone.translations = nil
+			redactExtension(clone.extensions, "detailTranslations")
+		case "instance":
+			redact(&clone.Instance)
+			redactExtension(clone.extensions, "path")
+		default:
+			redactExtension(clone.extensions, name)
+		}
+	}
+	return &clone
+}
+
+// redactExtension replaces the extension member with the redaction marker, if it is set.
+func redactExtension(extensions map[string]any, name string) {
+	if _, ok := extensions[name]; ok {
+		extensions[name] = redacted
+	}
+}
+
+// redact replaces the member with the redaction marker, an empty member is left empty.
+func redact(member *string) {
+	if *member != "" {
+		*member = redacted
+	}
+}
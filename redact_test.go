@@ -0,0 +1,74 @@
+package problemdetail_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestProblemDetail_Redact(t *testing.T) {
+	pd := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+		problemdetail.WithInstance("/account/12345/msgs/abc"),
+		problemdetail.WithExtension("balance", 30),
+	)
+	pd.Status = 403
+
+	safe := pd.Redact()
+	expectTrue(t, safe.Type == "https://example.com/probs/out-of-credit")
+	expectTrue(t, safe.Title == "You do not have enough credit.")
+	expectTrue(t, safe.Status == 403)
+	expectTrue(t, safe.Detail == "[redacted]")
+	expectTrue(t, safe.Instance == "[redacted]")
+	expectTrue(t, safe.Extensions()["balance"] == 30)
+
+	safe = pd.Redact("balance", "instance", "unknown")
+	expectTrue(t, safe.Detail == "Your current balance is 30, but that costs 50.")
+	expectTrue(t, safe.Instance == "[redacted]")
+	expectTrue(t, safe.Extensions()["balance"] == "[redacted]")
+	_, ok := safe.Extensions()["unknown"]
+	expectTrue(t, !ok)
+
+	expectTrue(t, pd.Detail == "Your current balance is 30, but that costs 50.")
+	expectTrue(t, pd.Instance == "/account/12345/msgs/abc")
+	expectTrue(t, pd.Extensions()["balance"] == 30)
+}
+
+func TestProblemDetail_RedactDetailTranslations(t *testing.T) {
+	pd := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+		problemdetail.WithDetailTranslations(map[string]string{
+			"en": "Your current balance is 30, but that costs 50.",
+			"id": "Saldo Anda 30, tetapi biayanya 50.",
+		}),
+	)
+
+	safe := pd.Redact()
+	expectTrue(t, safe.Detail == "[redacted]")
+	expectTrue(t, safe.Extensions()["detailTranslations"] == "[redacted]")
+	expectTrue(t, !strings.Contains(fmt.Sprint(safe.Fields()), "balance"))
+	expectTrue(t, !strings.Contains(fmt.Sprint(safe.Fields()), "Saldo"))
+
+	safe = pd.Redact("instance")
+	_, ok := safe.Extensions()["detailTranslations"].(map[string]string)
+	expectTrue(t, ok)
+	_, ok = pd.Extensions()["detailTranslations"].(map[string]string)
+	expectTrue(t, ok)
+}
+
+func TestProblemDetail_RedactRequestPath(t *testing.T) {
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	pd := problemdetail.New("https://example.com/probs/not-found", problemdetail.WithRequest(req))
+	expectTrue(t, pd.Instance == "/users/42")
+
+	safe := pd.Redact()
+	expectTrue(t, safe.Instance == "[redacted]")
+	expectTrue(t, safe.Extensions()["path"] == "[redacted]")
+	expectTrue(t, safe.Extensions()["method"] == "GET")
+	expectTrue(t, !strings.Contains(fmt.Sprint(safe.Fields()), "/users/42"))
+	expectTrue(t, pd.Extensions()["path"] == "/users/42")
+}
@@ -0,0 +1,149 @@
+package problemdetail
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// aliases maps the short codes registered by RegisterTypeAlias to their type URI.
+var aliases = struct {
+	sync.RWMutex
+	uris map[string]string
+}{uris: make(map[string]string)}
+
+// RegisterTypeAlias registers a short code for the given type URI, so New(code) creates a problem detail of that
+// type. For example, after RegisterTypeAlias("out-of-credit", "https://example.com/probs/out-of-credit"),
+// New("out-of-credit") is the same as New("https://example.com/probs/out-of-credit"). Registering an existing code
+// replaces its URI. It is safe for concurrent use.
+func RegisterTypeAlias(code, uri string) {
+	aliases.Lock()
+	defer aliases.Unlock()
+	aliases.uris[code] = uri
+}
+
+// resolveType returns the type URI registered for typ, or typ itself if it is not a registered code.
+func resolveType(typ string) string {
+	aliases.RLock()
+	defer aliases.RUnlock()
+	if uri, ok := aliases.uris[typ]; ok {
+		return uri
+	}
+	return typ
+}
+
+// typeBaseURL is the base URL set by SetTypeBaseURL, nil means the relative types are written as they are.
+var typeBaseURL atomic.Pointer[url.URL]
+
+// SetTypeBaseURL sets the base URL the relative problem types are resolved against at write time, for example with
+// "https://api.example.com" the type "/errors/out-of-credit" is written as
+// "https://api.example.com/errors/out-of-credit". So the same catalog of types serves several environments with
+// different hosts. Absolute types, such as Untyped, are written as they are. An empty base restores the default, which
+// writes the relative types as they are.
+//
+// It panics if base is not an absolute URL, since it is meant to be set once at program start. It is safe for
+// concurrent use.
+func SetTypeBaseURL(base string) {
+	if base == "" {
+		typeBaseURL.Store(nil)
+		return
+	}
+	u, err := url.Parse(base)
+	if err != nil || !u.IsAbs() {
+		panic(fmt.Sprintf("problemdetail: SetTypeBaseURL(%q): base is not an absolute URL", base))
+	}
+	typeBaseURL.Store(u)
+}
+
+// resolveTypeBase resolves ProblemDetail.Type against the base URL set by WithContextDefaults, or else by
+// SetTypeBaseURL, if it is relative.
+func (p *ProblemDetail) resolveTypeBase() {
+	base := p.typeBase
+	if base == nil {
+		base = typeBaseURL.Load()
+	}
+	if base == nil || p.Type == "" {
+		return
+	}
+	ref, err := url.Parse(p.Type)
+	if err != nil || ref.IsAbs() {
+		return // an invalid type is reported by the validation.
+	}
+	p.Type = base.ResolveReference(ref).String()
+}
+
+// typeInfo is the canonical title and status of a problem type registered by RegisterType.
+type typeInfo struct {
+	title  string
+	status int
+}
+
+// types maps the type URIs registered by RegisterType to their canonical title and status.
+var types = struct {
+	sync.RWMutex
+	infos map[string]typeInfo
+}{infos: make(map[string]typeInfo)}
+
+// RegisterType registers the canonical title and status code of a problem type. Registering an existing type replaces
+// its title and status. It is safe for concurrent use.
+func RegisterType(uri, title string, status int) {
+	types.Lock()
+	defer types.Unlock()
+	types.infos[uri] = typeInfo{title: title, status: status}
+}
+
+// StatusFromType returns the canonical status code of a type registered by RegisterType, the uri can also be a code
+// registered by RegisterTypeAlias. It returns false if the type is not registered.
+func StatusFromType(uri string) (int, bool) {
+	uri = resolveType(uri)
+	types.RLock()
+	defer types.RUnlock()
+	info, ok := types.infos[uri]
+	return info.status, ok
+}
+
+// errorStatus is a status code registered by RegisterError for the errors that match target.
+type errorStatus struct {
+	target error
+	status int
+}
+
+// errorStatuses are the mappings registered by RegisterError, in registration order.
+var errorStatuses = struct {
+	sync.RWMutex
+	mappings []errorStatus
+}{}
+
+// RegisterError registers the status code of the errors that match target with errors.Is, so FromError and WriteError
+// turn a domain error, such as a sql.ErrNoRows or a package-level sentinel, into a problem detail with that status.
+// Registering an existing target replaces its status. When an error matches several targets, the first registered one
+// wins. A target whose type is not comparable, such as a struct holding a slice, is only matched by its Is method, and
+// registering it again adds another mapping instead of replacing its status. It is safe for concurrent use.
+func RegisterError(target error, status int) {
+	errorStatuses.Lock()
+	defer errorStatuses.Unlock()
+	canCompare := target == nil || reflect.TypeOf(target).Comparable()
+	for i, m := range errorStatuses.mappings {
+		if canCompare && m.target == target {
+			errorStatuses.mappings[i].status = status
+			return
+		}
+	}
+	errorStatuses.mappings = append(errorStatuses.mappings, errorStatus{target: target, status: status})
+}
+
+// statusFromError returns the status code registered by RegisterError for err, it returns false if err matches no
+// registered target.
+func statusFromError(err error) (int, bool) {
+	errorStatuses.RLock()
+	defer errorStatuses.RUnlock()
+	for _, m := range errorStatuses.mappings {
+		if errors.Is(err, m.target) {
+			return m.status, true
+		}
+	}
+	return 0, false
+}
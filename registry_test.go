@@ -0,0 +1,65 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestRegisterTypeAlias(t *testing.T) {
+	problemdetail.RegisterTypeAlias("out-of-credit", "https://example.com/probs/out-of-credit")
+
+	pd := problemdetail.New("out-of-credit")
+	expectTrue(t, pd.Type == "https://example.com/probs/out-of-credit")
+
+	pd = problemdetail.New("https://example.com/probs/product-not-found")
+	expectTrue(t, pd.Type == "https://example.com/probs/product-not-found")
+}
+
+func TestStatusFromType(t *testing.T) {
+	problemdetail.RegisterType("https://example.com/probs/insufficient-funds", "Insufficient funds.", 402)
+	problemdetail.RegisterTypeAlias("insufficient-funds", "https://example.com/probs/insufficient-funds")
+
+	status, ok := problemdetail.StatusFromType("https://example.com/probs/insufficient-funds")
+	expectTrue(t, ok)
+	expectTrue(t, status == 402)
+
+	status, ok = problemdetail.StatusFromType("insufficient-funds")
+	expectTrue(t, ok)
+	expectTrue(t, status == 402)
+
+	status, ok = problemdetail.StatusFromType("https://example.com/probs/unregistered")
+	expectTrue(t, !ok)
+	expectTrue(t, status == 0)
+}
+
+func TestSetTypeBaseURL(t *testing.T) {
+	problemdetail.SetTypeBaseURL("https://api.example.com/v1/")
+	t.Cleanup(func() { problemdetail.SetTypeBaseURL("") })
+
+	data := problemdetail.New("errors/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+	)
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Type == "https://api.example.com/v1/errors/out-of-credit")
+
+	data = problemdetail.New("/errors/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+	)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Type == "https://api.example.com/errors/out-of-credit")
+
+	data = problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Type == problemdetail.Untyped)
+
+	defer func() { expectTrue(t, recover() != nil) }()
+	problemdetail.SetTypeBaseURL("/relative")
+}
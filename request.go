@@ -0,0 +1,61 @@
+package problemdetail
+
+import "net/http"
+
+// requestEcho is the part of the failing request that is echoed in the problem detail by WithRequest.
+type requestEcho struct {
+	// set reports whether WithRequest is applied.
+	set bool
+
+	method, path, query string
+
+	// includeQuery keeps the query of the request in the path extension member and the instance, see WithIncludeQuery.
+	includeQuery bool
+
+	// instance is the instance set from the request, empty if the instance is set otherwise.
+	instance string
+}
+
+// WithRequest echoes the method and the path of the failing request in the method and path extension members, so the
+// support staff can see what was requested without correlating the logs. If the instance is not set, it is set to
+// the path. The query is stripped, since it may carry sensitive parameters such as tokens, unless WithIncludeQuery is
+// also given. A nil request is a no-op.
+func WithRequest(r *http.Request) Option {
+	return func(pd *ProblemDetail) {
+		if r == nil {
+			return
+		}
+		pd.request.set = true
+		pd.request.method = r.Method
+		pd.request.path = r.URL.EscapedPath()
+		pd.request.query = r.URL.RawQuery
+		pd.echoRequest()
+	}
+}
+
+// WithIncludeQuery keeps the query of the request echoed by WithRequest, in both the path extension member and the
+// instance. It can be given before or after WithRequest.
+func WithIncludeQuery() Option {
+	return func(pd *ProblemDetail) {
+		pd.request.includeQuery = true
+		pd.echoRequest()
+	}
+}
+
+// echoRequest sets the extension members and the instance from the request echoed by WithRequest, if any. The
+// instance is only replaced if it is unset or set from the request.
+func (p *ProblemDetail) echoRequest() {
+	if !p.request.set {
+		return
+	}
+	path := p.request.path
+	if p.request.includeQuery && p.request.query != "" {
+		path += "?" + p.request.query
+	}
+	WithExtension("method", p.request.method)(p)
+	WithExtension("path", path)(p)
+	if p.Instance == "" || p.Instance == p.request.instance {
+		p.Instance = path
+		p.request.instance = path
+	}
+}
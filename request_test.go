@@ -0,0 +1,53 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWithRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/accounts/12345/transfers?token=secret", nil)
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithRequest(req),
+	)
+	expectTrue(t, data.Instance == "/accounts/12345/transfers")
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expRaw := `{"type":"about:blank","title":"Forbidden","status":403,"instance":"/accounts/12345/transfers","method":"POST","path":"/accounts/12345/transfers"}`
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == expRaw)
+}
+
+func TestWithRequest_WithIncludeQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/search?q=go", nil)
+
+	for _, opts := range [][]problemdetail.Option{
+		{problemdetail.WithRequest(req), problemdetail.WithIncludeQuery()},
+		{problemdetail.WithIncludeQuery(), problemdetail.WithRequest(req)},
+	} {
+		data := problemdetail.New(problemdetail.Untyped, opts...)
+		expectTrue(t, data.Instance == "/search?q=go")
+		expectTrue(t, data.Extensions()["path"] == "/search?q=go")
+	}
+}
+
+func TestWithRequest_KeepsInstance(t *testing.T) {
+	req := httptest.NewRequest("GET", "/search?q=go", nil)
+
+	for _, opts := range [][]problemdetail.Option{
+		{problemdetail.WithInstance("/probs/1"), problemdetail.WithRequest(req), problemdetail.WithIncludeQuery()},
+		{problemdetail.WithRequest(req), problemdetail.WithInstance("/probs/1"), problemdetail.WithIncludeQuery()},
+	} {
+		data := problemdetail.New(problemdetail.Untyped, opts...)
+		expectTrue(t, data.Instance == "/probs/1")
+		expectTrue(t, data.Extensions()["method"] == "GET")
+	}
+
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithRequest(nil))
+	expectTrue(t, data.Instance == "" && len(data.Extensions()) == 0)
+}
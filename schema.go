@@ -0,0 +1,243 @@
+package problemdetail
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// schemas maps the type URIs registered by RegisterExtensionSchema to the schema of their extension members.
+var schemas = struct {
+	sync.RWMutex
+	byType map[string]*schema
+}{byType: make(map[string]*schema)}
+
+// RegisterExtensionSchema registers the JSON Schema that the extension members of the problem details of the given
+// type must conform to, so a drift in their shape is caught by the contract tests. The schema describes the object of
+// the extension members, for example:
+//
+//	{"type":"object","required":["balance"],"properties":{"balance":{"type":"integer","minimum":0}}}
+//
+// The problem details with LExtensionSchema, which is part of LStrict, are checked by the writers right before they
+// are encoded, a mismatch is reported as ErrExtensionSchema. The fields of a type that embeds ProblemDetail are
+// checked as extension members too. Without a registered schema, no check runs. The uri can also be a code registered
+// by RegisterTypeAlias. Registering an existing type replaces its schema. It is safe for concurrent use.
+//
+// Only a subset of JSON Schema is supported: the type, enum, properties, required, additionalProperties (as a boolean),
+// items, minimum, maximum, minLength, maxLength and pattern keywords. The other keywords are ignored. It panics if the
+// schema is not valid JSON or has an invalid pattern, since it is meant to be registered at program start.
+func RegisterExtensionSchema(uri string, schemaJSON []byte) {
+	s, err := parseSchema(schemaJSON)
+	if err != nil {
+		panic(fmt.Sprintf("problemdetail: RegisterExtensionSchema(%q): %v", uri, err))
+	}
+	schemas.Lock()
+	defer schemas.Unlock()
+	schemas.byType[resolveType(uri)] = s
+}
+
+// validateExtensionSchema ensures that the extension members conform to the schema registered for the type, if any.
+// The members are checked as they are encoded by the JSON writers, so the fields of a type that embeds ProblemDetail
+// are checked along with the members added by the options.
+func validateExtensionSchema(pd ProblemDetailer) error {
+	p := baseOf(pd)
+	if p == nil || !p.flags.has(LExtensionSchema) {
+		return nil
+	}
+	schemas.RLock()
+	s, ok := schemas.byType[p.Type]
+	schemas.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	raw, err := marshalJSON(pd)
+	if err != nil {
+		return nil // a value that cannot be encoded is reported by validateExtensions.
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var members map[string]any
+	if err := dec.Decode(&members); err != nil {
+		return nil // the encoding of a json.Marshaler is not an object, there are no extension members to check.
+	}
+	for name := range standardMembers {
+		delete(members, name)
+	}
+	return errors.Join(s.validate("", members)...)
+}
+
+// schema is the supported subset of a JSON Schema.
+//
+// ref: https://json-schema.org/draft/2020-12/json-schema-validation
+type schema struct {
+	Type                 schemaTypes        `json:"type"`
+	Enum                 []any              `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+
+	// pattern is the compiled Pattern.
+	pattern *regexp.Regexp
+}
+
+// schemaTypes is the type keyword, which is either a single type or a list of types.
+type schemaTypes []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// parseSchema decodes the schema and compiles its patterns.
+func parseSchema(data []byte) (*schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // the enum values are compared to the members decoded the same way.
+	var s schema
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile compiles the pattern of s and of its subschemas.
+func (s *schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	for _, sub := range s.Properties {
+		if err := sub.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// validate returns the mismatches of v, a value decoded with json.Decoder.UseNumber, located by the JSON Pointer path.
+func (s *schema) validate(path string, v any) []error {
+	mismatch := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %q: %s", ErrExtensionSchema, path, fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Type) > 0 && !s.Type.match(v) {
+		return []error{mismatch("expected %s", strings.Join(s.Type, " or "))}
+	}
+	if len(s.Enum) > 0 && !s.inEnum(v) {
+		return []error{mismatch("not one of the enum values")}
+	}
+
+	var errs []error
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, mismatch("missing required member %q", name))
+			}
+		}
+		for _, name := range sortedKeys(v) {
+			sub, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, mismatch("unexpected member %q", name))
+				}
+				continue
+			}
+			errs = append(errs, sub.validate(path+"/"+pointerEscaper.Replace(name), v[name])...)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, s.Items.validate(fmt.Sprintf("%s/%d", path, i), item)...)
+			}
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			errs = append(errs, mismatch("less than the minimum %v", *s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			errs = append(errs, mismatch("greater than the maximum %v", *s.Maximum))
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			errs = append(errs, mismatch("shorter than the minimum length %d", *s.MinLength))
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs = append(errs, mismatch("longer than the maximum length %d", *s.MaxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			errs = append(errs, mismatch("does not match the pattern %q", s.Pattern))
+		}
+	}
+	return errs
+}
+
+// inEnum reports whether v is one of the enum values.
+func (s *schema) inEnum(v any) bool {
+	for _, value := range s.Enum {
+		if reflect.DeepEqual(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether v is of one of the types.
+func (t schemaTypes) match(v any) bool {
+	for _, typ := range t {
+		if jsonTypeOf(v) == typ || (typ == "number" && jsonTypeOf(v) == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf returns the JSON Schema type of v, a value decoded with json.Decoder.UseNumber.
+func jsonTypeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestRegisterExtensionSchema(t *testing.T) {
+	const typ = "https://example.com/probs/schema-out-of-credit"
+	problemdetail.RegisterExtensionSchema(typ, []byte(`{
+		"type": "object",
+		"required": ["balance"],
+		"additionalProperties": false,
+		"properties": {
+			"balance": {"type": "integer", "minimum": 0},
+			"currency": {"enum": ["EUR", "USD"]},
+			"accounts": {"type": "array", "items": {"type": "string", "pattern": "^/account/"}}
+		}
+	}`))
+
+	newData := func(opts ...problemdetail.Option) *problemdetail.ProblemDetail {
+		opts = append([]problemdetail.Option{
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+			problemdetail.WithInstance("/account/12345/msgs/abc"),
+		}, opts...)
+		return problemdetail.New(typ, opts...)
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, newData(
+		problemdetail.WithExtension("balance", 30),
+		problemdetail.WithExtension("currency", "EUR"),
+		problemdetail.WithExtension("accounts", []string{"/account/12345"}),
+	), 403)
+	expectTrue(t, err == nil)
+
+	tests := []struct {
+		opts    []problemdetail.Option
+		message string
+	}{
+		{opts: nil, message: `missing required member "balance"`},
+		{opts: []problemdetail.Option{problemdetail.WithExtension("balance", "30")}, message: `"/balance": expected integer`},
+		{opts: []problemdetail.Option{problemdetail.WithExtension("balance", -1)}, message: "less than the minimum"},
+		{
+			opts: []problemdetail.Option{
+				problemdetail.WithExtension("balance", 30),
+				problemdetail.WithExtension("currency", "IDR"),
+			},
+			message: "not one of the enum values",
+		},
+		{
+			opts: []problemdetail.Option{
+				problemdetail.WithExtension("balance", 30),
+				problemdetail.WithExtension("accounts", []string{"12345"}),
+			},
+			message: `"/accounts/0": does not match the pattern`,
+		},
+		{
+			opts: []problemdetail.Option{
+				problemdetail.WithExtension("balance", 30),
+				problemdetail.WithExtension("traceId", "abc"),
+			},
+			message: `unexpected member "traceId"`,
+		},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		err := problemdetail.WriteJSON(rec, newData(tt.opts...), 403)
+		expectTrue(t, errors.Is(err, problemdetail.ErrExtensionSchema))
+		expectTrue(t, strings.Contains(err.Error(), tt.message))
+	}
+
+	data := newData(problemdetail.WithValidateLevel(problemdetail.LAllRequired))
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, err == nil)
+}
+
+func TestRegisterExtensionSchema_EmbeddingType(t *testing.T) {
+	const typ = "https://example.com/probs/schema-embedded-out-of-credit"
+	problemdetail.RegisterExtensionSchema(typ, []byte(`{
+		"type": "object",
+		"required": ["balance", "accounts"],
+		"additionalProperties": false,
+		"properties": {
+			"balance": {"type": "integer", "minimum": 0},
+			"accounts": {"type": "array", "items": {"type": "string", "pattern": "^/account/"}}
+		}
+	}`))
+
+	newData := func(balance int32, accounts ...string) *BalanceProblemDetail {
+		return &BalanceProblemDetail{
+			ProblemDetail: problemdetail.New(
+				typ,
+				problemdetail.WithTitle("You do not have enough credit."),
+				problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+				problemdetail.WithInstance("/account/12345/msgs/abc"),
+			),
+			Balance:  balance,
+			Accounts: accounts,
+		}
+	}
+
+	err := problemdetail.WriteJSON(httptest.NewRecorder(), newData(30, "/account/12345"), 403)
+	expectTrue(t, err == nil)
+
+	rec := httptest.NewRecorder()
+	err = problemdetail.WriteJSON(rec, newData(-1, "12345"), 403)
+	expectTrue(t, errors.Is(err, problemdetail.ErrExtensionSchema))
+	expectTrue(t, errors.Is(err, problemdetail.ErrValidation))
+	expectTrue(t, strings.Contains(err.Error(), `"/balance": less than the minimum`))
+	expectTrue(t, strings.Contains(err.Error(), `"/accounts/0": does not match the pattern`))
+	expectTrue(t, rec.Body.Len() == 0)
+}
+
+func TestRegisterExtensionSchema_Invalid(t *testing.T) {
+	defer func() { expectTrue(t, recover() != nil) }()
+	problemdetail.RegisterExtensionSchema("https://example.com/probs/invalid", []byte(`{"pattern":"("}`))
+}
@@ -0,0 +1,40 @@
+package problemdetail
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Set of severities accepted by WithSeverity.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityError    = "error"
+	SeverityCritical = "critical"
+)
+
+// WithSeverity adds the severity extension member, so clients and log processors can triage problems consistently.
+// The level must be one of SeverityInfo, SeverityWarning, SeverityError or SeverityCritical, any other level is
+// reported as ErrSeverityFormat when the problem detail is validated.
+func WithSeverity(level string) Option {
+	return func(pd *ProblemDetail) {
+		switch level {
+		case SeverityInfo, SeverityWarning, SeverityError, SeverityCritical:
+			WithExtension("severity", level)(pd)
+		default:
+			pd.errs = append(pd.errs, fmt.Errorf("%w: %q", ErrSeverityFormat, level))
+		}
+	}
+}
+
+// WithSeverityHeader makes the writers also set the Severity header to the severity extension member set by
+// WithSeverity. Without a severity, no header is set.
+func WithSeverityHeader() Option {
+	return func(pd *ProblemDetail) {
+		pd.headers = append(pd.headers, func(h http.Header, p *ProblemDetail) {
+			if level, ok := p.extensions["severity"].(string); ok {
+				h.Set("Severity", level)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteJSON_WithSeverity(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithSeverity(problemdetail.SeverityCritical),
+		problemdetail.WithSeverityHeader(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 500)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.TrimSpace(rec.Body.String()) == `{"type":"about:blank","title":"Internal Server Error","status":500,"severity":"critical"}`)
+	expectTrue(t, rec.Header().Get("Severity") == "critical")
+}
+
+func TestWriteJSON_WithSeverityRedacted(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithSeverity(problemdetail.SeverityCritical),
+		problemdetail.WithSeverityHeader(),
+	).Redact("severity")
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 500)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Severity") == "[redacted]")
+}
+
+func TestWriteJSON_WithSeverityWithoutHeader(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithSeverity(problemdetail.SeverityWarning),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 400)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Severity") == "")
+}
+
+func TestWriteJSON_WithInvalidSeverity(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithSeverity("fatal"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 500)
+	expectTrue(t, errors.Is(err, problemdetail.ErrSeverityFormat))
+}
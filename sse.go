@@ -0,0 +1,50 @@
+package problemdetail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WriteSSE writes the problem detail as a Server-Sent Events frame, with the given event name and the JSON encoding
+// of the problem detail as data. So a long-lived event stream can report a problem mid-stream.
+//
+// Since the stream is already started with its own status code, the status code is not written and ProblemDetail.Status
+// is kept as it is. The content type is set to text/event-stream if it is not set yet, and the frame is flushed right
+// away. If event is empty, the event field is omitted and the frame is a "message" event.
+//
+// If the problem detail is invalid, an error is returned.
+//
+// ref: https://html.spec.whatwg.org/multipage/server-sent-events.html
+func WriteSSE(w http.ResponseWriter, pd ProblemDetailer, event string) error {
+	if strings.ContainsAny(event, "\r\n") {
+		return errors.New("WriteSSE: event name must not contain a line break")
+	}
+	data, err := encode(pd, marshalJSON)
+	if err != nil {
+		return fmt.Errorf("WriteSSE: %w", err)
+	}
+
+	var frame bytes.Buffer
+	if event != "" {
+		frame.WriteString("event: ")
+		frame.WriteString(event)
+		frame.WriteByte('\n')
+	}
+	frame.WriteString("data: ")
+	frame.Write(data) // JSON encoding never contains a raw line break.
+	frame.WriteString("\n\n")
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	if _, err := w.Write(frame.Bytes()); err != nil {
+		return fmt.Errorf("WriteSSE: %w", stageError(ErrIO, err))
+	}
+	if err := flush(w); err != nil {
+		return fmt.Errorf("WriteSSE: %w", stageError(ErrIO, err))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteSSE(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+	data.WriteStatus(503)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteSSE(rec, data, "problem")
+	expectTrue(t, err == nil)
+
+	exp := "event: problem\ndata: {\"type\":\"about:blank\",\"title\":\"Service Unavailable\",\"status\":503}\n\n"
+	expectTrue(t, rec.Body.String() == exp)
+	expectTrue(t, rec.Code == 200)
+	expectTrue(t, rec.Flushed)
+	expectTrue(t, rec.Header().Get("Content-Type") == "text/event-stream")
+}
+
+func TestWriteSSE_WithoutEvent(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+	data.WriteStatus(503)
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	err := problemdetail.WriteSSE(rec, data, "")
+	expectTrue(t, err == nil)
+
+	exp := "data: {\"type\":\"about:blank\",\"title\":\"Service Unavailable\",\"status\":503}\n\n"
+	expectTrue(t, rec.Body.String() == exp)
+	expectTrue(t, rec.Header().Get("Content-Type") == "text/event-stream; charset=utf-8")
+}
+
+func TestWriteSSE_WithInvalidEvent(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+	data.WriteStatus(503)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteSSE(rec, data, "problem\ndata: injected")
+	expectTrue(t, err != nil)
+	expectTrue(t, rec.Body.Len() == 0)
+}
@@ -0,0 +1,81 @@
+package problemdetail
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// statusTitleFunc is the function set by SetStatusTitleFunc, nil means http.StatusText.
+var statusTitleFunc atomic.Pointer[func(code int) string]
+
+// SetStatusTitleFunc sets the function that returns the title of a status code. It is used for the untyped problems
+// (see ProblemDetail.WriteStatus), by WithTitleFromStatus, WithDefaults and Errorf. So a team can title nonstandard
+// status codes, such as 419 or 499, or localize the titles. The default is http.StatusText, which returns an empty
+// title for an unknown code; a custom function can fall back to a title like "Unknown" instead. A nil function
+// restores the default. It is safe for concurrent use, but it is meant to be set once at program start.
+func SetStatusTitleFunc(fn func(code int) string) {
+	if fn == nil {
+		statusTitleFunc.Store(nil)
+		return
+	}
+	statusTitleFunc.Store(&fn)
+}
+
+// statusTitle returns the title of the status code, using the function set by SetStatusTitleFunc.
+func statusTitle(code int) string {
+	if fn := statusTitleFunc.Load(); fn != nil {
+		return (*fn)(code)
+	}
+	return http.StatusText(code)
+}
+
+// WithTitleFromStatus makes the writers set ProblemDetail.Title to the title of the status code, see
+// SetStatusTitleFunc, even if the problem detail is typed.
+func WithTitleFromStatus() Option {
+	return func(pd *ProblemDetail) { pd.titleFromStatus = true }
+}
+
+// titleMaxRunes is the maximum length of a title derived by WithTitleFromDetail, the "…" marker included.
+const titleMaxRunes = 80
+
+// WithTitleFromDetail makes the writers derive ProblemDetail.Title from the first sentence of ProblemDetail.Detail,
+// for quick error construction, when the problem detail has no title of its own: the title is empty, or the problem
+// detail is untyped, whose title is otherwise the status text. The trailing punctuation of the sentence is removed,
+// and a sentence longer than 80 characters is cut and ends with the "…" marker. Without detail, the title is left as
+// it is.
+func WithTitleFromDetail() Option {
+	return func(pd *ProblemDetail) { pd.titleFromDetail = true }
+}
+
+// deriveTitleFromDetail sets the title to the first sentence of the detail if WithTitleFromDetail is set.
+func (p *ProblemDetail) deriveTitleFromDetail() {
+	if !p.titleFromDetail || p.Detail == "" || (p.Title != "" && !p.IsUntyped()) {
+		return
+	}
+	if title := firstSentence(p.Detail); title != "" {
+		p.Title = title
+	}
+}
+
+// firstSentence returns the first sentence of s, without its trailing punctuation, capped to titleMaxRunes.
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		s = s[:i]
+	}
+	for i, r := range s {
+		if strings.ContainsRune(".!?", r) && (i+1 == len(s) || s[i+1] == ' ') {
+			s = s[:i]
+			break
+		}
+	}
+	s = strings.TrimRight(s, ".!?,;: ")
+
+	if utf8.RuneCountInString(s) <= titleMaxRunes {
+		return s
+	}
+	runes := []rune(s)[:titleMaxRunes-1]
+	return strings.TrimRight(string(runes), " ") + truncationMarker
+}
@@ -0,0 +1,100 @@
+package problemdetail_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestSetStatusTitleFunc(t *testing.T) {
+	problemdetail.SetStatusTitleFunc(func(code int) string {
+		switch code {
+		case 419:
+			return "Page Expired"
+		case 499:
+			return "Client Closed Request"
+		}
+		if text := http.StatusText(code); text != "" {
+			return text
+		}
+		return "Unknown"
+	})
+	t.Cleanup(func() { problemdetail.SetStatusTitleFunc(nil) })
+
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 419)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Title == "Page Expired")
+
+	data.WriteStatus(404)
+	expectTrue(t, data.Title == "Not Found")
+
+	data.WriteStatus(598)
+	expectTrue(t, data.Title == "Unknown")
+
+	expectTrue(t, problemdetail.Errorf(problemdetail.Untyped, 499, "canceled").Title == "Client Closed Request")
+
+	problemdetail.SetStatusTitleFunc(nil)
+	data.WriteStatus(419)
+	expectTrue(t, data.Title == "")
+}
+
+func TestWriteJSON_WithTitleFromStatus(t *testing.T) {
+	data := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithTitleFromStatus(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Title == "Forbidden")
+}
+
+func TestWriteJSON_WithTitleFromDetail(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50. Top up your account."),
+		problemdetail.WithTitleFromDetail(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Title == "Your current balance is 30, but that costs 50")
+
+	data = problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithDetail("Your current balance is 30, but that costs 50."),
+		problemdetail.WithTitleFromDetail(),
+	)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Title == "You do not have enough credit.")
+
+	data = problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail(strings.Repeat("credit ", 20)+"is not enough!"),
+		problemdetail.WithTitleFromDetail(),
+	)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, utf8.RuneCountInString(data.Title) <= 80)
+	expectTrue(t, strings.HasSuffix(data.Title, "…"))
+	expectTrue(t, strings.HasPrefix(data.Title, "credit credit"))
+
+	data = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitleFromDetail(),
+	)
+	err = problemdetail.WriteJSON(httptest.NewRecorder(), data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, data.Title == "Forbidden")
+}
@@ -0,0 +1,38 @@
+package problemdetail
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ProblemTrailer is the name of the trailer that WriteStreamingError sets to the problem detail.
+const ProblemTrailer = "Problem"
+
+// DeclareProblemTrailer adds ProblemTrailer to the Trailer header, so the client knows up front that the response may
+// end with a problem. It must be called before the status code or the first byte of the body is written.
+func DeclareProblemTrailer(w http.ResponseWriter) {
+	for _, name := range w.Header().Values("Trailer") {
+		if http.CanonicalHeaderKey(name) == ProblemTrailer {
+			return
+		}
+	}
+	w.Header().Add("Trailer", ProblemTrailer)
+}
+
+// WriteStreamingError reports a problem that happens after the status code and part of the body of a streaming
+// response are already written, in the ProblemTrailer trailer, whose value is the JSON encoding of the problem detail.
+// The trailer is declared up front if the response is not committed yet, but it is sent even if it is not declared,
+// as net/http allows. It requires a chunked HTTP/1.1 response or HTTP/2, and the handler must return after it.
+//
+// Since the status code is already written, ProblemDetail.Status is kept as it is.
+//
+// If the problem detail is invalid, an error is returned.
+func WriteStreamingError(w http.ResponseWriter, pd ProblemDetailer) error {
+	data, err := encode(pd, marshalJSON)
+	if err != nil {
+		return fmt.Errorf("WriteStreamingError: %w", err)
+	}
+	DeclareProblemTrailer(w)
+	w.Header().Set(http.TrailerPrefix+ProblemTrailer, string(data)) // JSON encoding never contains a raw line break.
+	return nil
+}
@@ -0,0 +1,41 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteStreamingError(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail("upstream closed the stream"),
+	)
+	data.WriteStatus(502)
+
+	rec := httptest.NewRecorder()
+	problemdetail.DeclareProblemTrailer(rec)
+	problemdetail.DeclareProblemTrailer(rec)
+	rec.WriteHeader(200)
+	_, _ = rec.Write([]byte(`{"items":[`))
+
+	err := problemdetail.WriteStreamingError(rec, data)
+	expectTrue(t, err == nil)
+
+	res := rec.Result()
+	expectTrue(t, res.StatusCode == 200)
+	expectTrue(t, len(res.Header.Values("Trailer")) == 1)
+	expRaw := `{"type":"about:blank","title":"Bad Gateway","status":502,"detail":"upstream closed the stream"}`
+	expectTrue(t, res.Trailer.Get(problemdetail.ProblemTrailer) == expRaw)
+}
+
+func TestWriteStreamingError_Invalid(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteStreamingError(rec, data)
+	expectTrue(t, errors.Is(err, problemdetail.ErrStatusRequired))
+	expectTrue(t, rec.Result().Trailer.Get(problemdetail.ProblemTrailer) == "")
+}
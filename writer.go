@@ -0,0 +1,124 @@
+package problemdetail
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Format is a serialization of the problem details.
+type Format int
+
+const (
+	// FormatJSON is application/problem+json.
+	FormatJSON Format = iota
+
+	// FormatXML is application/problem+xml.
+	FormatXML
+)
+
+// Writer writes problem details to the response writer with its own configuration, so two parts of a program, such
+// as a public API and an internal one, can write problem details differently. The zero value is ready to use and
+// follows the package settings, such as SetEscapeHTML, the same as WriteJSON, WriteXML and Write, which delegate to a
+// zero Writer. A Writer is safe for concurrent use once it is configured.
+type Writer struct {
+	defaultFormat Format
+	sanitize      func(pd ProblemDetailer)
+	escapeHTML    bool
+	escapeHTMLSet bool
+	charset       string
+}
+
+// WriterOption customizes a Writer.
+type WriterOption func(*Writer)
+
+// NewWriter returns a Writer configured by the given options.
+func NewWriter(opts ...WriterOption) *Writer {
+	wr := new(Writer)
+	for _, opt := range opts {
+		opt(wr)
+	}
+	return wr
+}
+
+// WithDefaultFormat sets the format that Negotiate writes when the request does not prefer one, that is when it has
+// no Accept header, it accepts any media type, or none of the media types it accepts is JSON or XML. The default is
+// FormatJSON.
+func WithDefaultFormat(f Format) WriterOption {
+	return func(wr *Writer) { wr.defaultFormat = f }
+}
+
+// WithSanitizer sets a function that is called with every problem detail after its validation, right before its
+// encoding, for example to redact the members that must not leave an internal network. It may modify the problem
+// detail in place.
+func WithSanitizer(fn func(pd ProblemDetailer)) WriterOption {
+	return func(wr *Writer) { wr.sanitize = fn }
+}
+
+// WithHTMLEscaping sets whether the JSON written by the Writer escapes the HTML characters <, > and & in strings,
+// regardless of the setting of SetEscapeHTML.
+func WithHTMLEscaping(enabled bool) WriterOption {
+	return func(wr *Writer) {
+		wr.escapeHTML = enabled
+		wr.escapeHTMLSet = true
+	}
+}
+
+// WithCharset sets the charset parameter of the Content-Type header, the default is utf-8. The body is not
+// transcoded, it is always UTF-8, the option is meant for the consumers that expect another spelling, such as UTF-8.
+func WithCharset(charset string) WriterOption {
+	return func(wr *Writer) { wr.charset = charset }
+}
+
+// defaultWriter is the Writer that the package-level writers delegate to.
+var defaultWriter Writer
+
+// JSON is like WriteJSON, but it follows the configuration of the Writer.
+func (wr *Writer) JSON(w http.ResponseWriter, pd ProblemDetailer, code int) error {
+	return write(w, nil, pd, code, wr.format(formatJSON))
+}
+
+// XML is like WriteXML, but it follows the configuration of the Writer.
+func (wr *Writer) XML(w http.ResponseWriter, pd ProblemDetailer, code int) error {
+	return write(w, nil, pd, code, wr.format(formatXML))
+}
+
+// Negotiate is like Write, but it follows the configuration of the Writer.
+func (wr *Writer) Negotiate(w http.ResponseWriter, r *http.Request, pd ProblemDetailer, code int) error {
+	addVary(w.Header(), "Accept")
+	var language string
+	if p := baseOf(pd); p != nil && len(p.translations) > 0 {
+		addVary(w.Header(), "Accept-Language")
+		language, _ = p.negotiateLanguage(r.Header.Values("Accept-Language"))
+	}
+
+	fallback := formatJSON
+	if wr.defaultFormat == FormatXML {
+		fallback = formatXML
+	}
+	f := wr.format(negotiateFormat(r.Header.Values("Accept"), fallback))
+	f.language = language
+	return write(w, r, pd, code, f)
+}
+
+// format returns f customized by the configuration of the Writer. The zero Writer returns f as it is.
+func (wr *Writer) format(f format) format {
+	mediaType, _, _ := strings.Cut(f.contentType, ";")
+	if wr.charset != "" {
+		f.contentType = mediaType + "; charset=" + wr.charset
+	}
+
+	encoder := f.encode
+	if wr.escapeHTMLSet && mediaType == "application/problem+json" {
+		escapeHTML := wr.escapeHTML
+		encoder = func(pd ProblemDetailer) ([]byte, error) { return encodeJSONWith(pd, escapeHTML) }
+	}
+	if wr.sanitize == nil {
+		f.encode = encoder
+		return f
+	}
+	f.encode = func(pd ProblemDetailer) ([]byte, error) {
+		wr.sanitize(pd)
+		return encoder(pd)
+	}
+	return f
+}
@@ -0,0 +1,112 @@
+package problemdetail_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriter_ZeroValue(t *testing.T) {
+	newProblem := func() *problemdetail.ProblemDetail {
+		return problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithDetail("a < b & c"),
+		)
+	}
+
+	exp := httptest.NewRecorder()
+	err := problemdetail.WriteJSON(exp, newProblem(), 400)
+	expectTrue(t, err == nil)
+
+	var wr problemdetail.Writer
+	rec := httptest.NewRecorder()
+	err = wr.JSON(rec, newProblem(), 400)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Body.String() == exp.Body.String())
+	expectTrue(t, rec.Header().Get("Content-Type") == exp.Header().Get("Content-Type"))
+}
+
+func TestWriter_WithHTMLEscaping(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail(`a < b & c \u0026`),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.NewWriter(problemdetail.WithHTMLEscaping(false)).JSON(rec, data, 400)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.Contains(rec.Body.String(), `"detail":"a < b & c \\u0026"`))
+
+	problemdetail.SetEscapeHTML(false)
+	t.Cleanup(func() { problemdetail.SetEscapeHTML(true) })
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.NewWriter(problemdetail.WithHTMLEscaping(true)).JSON(rec, data, 400)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.Contains(rec.Body.String(), `"detail":"a \u003c b \u0026 c \\u0026"`))
+
+	data = problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithEnvelope("<error>"),
+		problemdetail.WithExtension("next", "/orders?page=2&size=10"),
+	)
+	rec = httptest.NewRecorder()
+	err = problemdetail.NewWriter(problemdetail.WithHTMLEscaping(false)).JSON(rec, data, 400)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.HasPrefix(rec.Body.String(), `{"<error>":{`))
+	expectTrue(t, strings.Contains(rec.Body.String(), `"next":"/orders?page=2&size=10"`))
+}
+
+func TestWriter_WithCharset(t *testing.T) {
+	wr := problemdetail.NewWriter(problemdetail.WithCharset("UTF-8"))
+	data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+
+	rec := httptest.NewRecorder()
+	err := wr.XML(rec, data, 404)
+	expectTrue(t, err == nil)
+	expectTrue(t, rec.Header().Get("Content-Type") == "application/problem+xml; charset=UTF-8")
+}
+
+func TestWriter_WithDefaultFormat(t *testing.T) {
+	wr := problemdetail.NewWriter(problemdetail.WithDefaultFormat(problemdetail.FormatXML))
+
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{accept: "", contentType: "application/problem+xml; charset=utf-8"},
+		{accept: "*/*", contentType: "application/problem+xml; charset=utf-8"},
+		{accept: "text/html", contentType: "application/problem+xml; charset=utf-8"},
+		{accept: "application/json", contentType: "application/problem+json; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		data := problemdetail.New(problemdetail.Untyped, problemdetail.WithValidateLevel(problemdetail.LStandard))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.accept)
+
+		rec := httptest.NewRecorder()
+		err := wr.Negotiate(rec, req, data, 404)
+		expectTrue(t, err == nil)
+		expectTrue(t, rec.Header().Get("Content-Type") == tt.contentType)
+	}
+}
+
+func TestWriter_WithSanitizer(t *testing.T) {
+	wr := problemdetail.NewWriter(problemdetail.WithSanitizer(func(pd problemdetail.ProblemDetailer) {
+		if p, ok := pd.(*problemdetail.ProblemDetail); ok {
+			p.Detail = ""
+		}
+	}))
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail("dial tcp 10.0.0.1:5432: connection refused"),
+	)
+
+	rec := httptest.NewRecorder()
+	err := wr.JSON(rec, data, 500)
+	expectTrue(t, err == nil)
+	expectTrue(t, !strings.Contains(rec.Body.String(), "10.0.0.1"))
+}
@@ -0,0 +1,283 @@
+package problemdetail
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// xmlOptions customizes the XML encoding of the ProblemDetail.
+type xmlOptions struct {
+	// prefix is the namespace prefix of every element, empty means the default namespace is used.
+	prefix string
+
+	// emitEmpty writes an empty element for every empty, but non-nil, slice.
+	emitEmpty bool
+
+	// omitStatus omits the status element.
+	omitStatus bool
+
+	// statusText writes the status code as the code attribute of the status element, and its text as the content.
+	statusText bool
+
+	// detailCDATA writes the content of the detail element as a CDATA section.
+	detailCDATA bool
+
+	// declaration prepends the XML declaration to the document, it does not require the document to be rewritten.
+	declaration bool
+}
+
+// WithXMLNamespacePrefix makes WriteXML bind the RFC 7807 namespace to the given prefix, instead of declaring it as
+// the default namespace. Every element inherits the prefix, for example:
+//
+//	<p:problem xmlns:p="urn:ietf:rfc:7807"><p:type>about:blank</p:type>...</p:problem>
+func WithXMLNamespacePrefix(prefix string) Option {
+	return func(pd *ProblemDetail) { pd.xml.prefix = prefix }
+}
+
+// WithEmitEmptyCollections makes WriteXML write a self-closing element, such as <accounts/>, for every empty but
+// non-nil slice, of both the extension members and the fields of the type that embeds ProblemDetail. By default,
+// encoding/xml writes nothing for an empty slice, the same as for a nil slice, so a consumer cannot tell "no accounts"
+// from "accounts absent". A nil slice still writes nothing. The empty elements are written after the other elements.
+func WithEmitEmptyCollections() Option {
+	return func(pd *ProblemDetail) { pd.xml.emitEmpty = true }
+}
+
+// WithXMLStatusText makes WriteXML write the status code as the code attribute of the status element, and the status
+// text as its content, for a schema that expects a human-readable status, for example:
+//
+//	<status code="403">Forbidden</status>
+//
+// The status text is the one given by SetStatusTitleFunc, http.StatusText by default. By default, the status element
+// only holds the numeric code.
+func WithXMLStatusText() Option {
+	return func(pd *ProblemDetail) { pd.xml.statusText = true }
+}
+
+// WithXMLDetailCDATA makes WriteXML write the content of the detail element as a CDATA section instead of escaped
+// character data, for a legacy consumer whose parser mishandles the escaped characters, for example:
+//
+//	<detail><![CDATA[Your balance is < 50 & your card expired.]]></detail>
+//
+// A "]]>" sequence in the detail, which would end the section, is split across two CDATA sections. By default, the
+// detail is escaped as any other element.
+func WithXMLDetailCDATA() Option {
+	return func(pd *ProblemDetail) { pd.xml.detailCDATA = true }
+}
+
+// WithXMLDeclaration makes WriteXML prepend the XML declaration to the document, for the strict parsers that reject a
+// document without it:
+//
+//	<?xml version="1.0" encoding="UTF-8"?>
+//
+// The declaration is followed by a newline. By default, no declaration is written.
+func WithXMLDeclaration() Option {
+	return func(pd *ProblemDetail) { pd.xml.declaration = true }
+}
+
+// appendXMLEmptyCollections appends an empty element for every empty, but non-nil, slice of both the fields of pd and
+// the extension members.
+func appendXMLEmptyCollections(raw []byte, pd ProblemDetailer, extensions map[string]any) ([]byte, error) {
+	var names []string
+	if v := reflect.Indirect(reflect.ValueOf(pd)); v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, ok := xmlFieldName(field)
+			if ok && isEmptySlice(v.Field(i)) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range sortedKeys(extensions) {
+		if isEmptySlice(reflect.ValueOf(extensions[name])) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return raw, nil
+	}
+
+	end := bytes.LastIndex(raw, []byte("</"))
+	if end < 0 {
+		return nil, errors.New("xml: root element is not closed")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(raw[:end])
+	for _, name := range names {
+		buf.WriteByte('<')
+		buf.WriteString(name)
+		buf.WriteString("/>")
+	}
+	buf.Write(raw[end:])
+	return buf.Bytes(), nil
+}
+
+// xmlFieldName returns the element name of the struct field as encoding/xml names it, it returns false if the field
+// is not encoded as a plain child element.
+func xmlFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() || field.Anonymous {
+		return "", false
+	}
+	tag := field.Tag.Get("xml")
+	if tag == "-" {
+		return "", false
+	}
+	name, flags, _ := strings.Cut(tag, ",")
+	if flags != "" && flags != "omitempty" {
+		return "", false // attributes, character data, comments and inner XML are not elements.
+	}
+	if strings.Contains(name, ">") {
+		return "", false // elements nested in a parent path are kept as encoding/xml writes them.
+	}
+	if _, local, ok := strings.Cut(name, " "); ok {
+		name = local
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// isEmptySlice reports whether v is an empty, but non-nil, slice. Byte slices are encoded as character data, so they
+// are not considered as collections.
+func isEmptySlice(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	return !v.IsNil() && v.Len() == 0
+}
+
+// rewriteXML re-serializes the XML document produced by encoding/xml according to the given options. An element
+// without content is written as a self-closing element.
+func rewriteXML(raw []byte, opts xmlOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	open := false // whether the last start tag is not closed yet, to make it self-closing if the element is empty.
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if _, end := tok.(xml.EndElement); open && !end {
+			buf.WriteByte('>')
+			open = false
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if depth == 1 && opts.omitStatus && tok.Name.Local == "status" {
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if depth == 1 && opts.statusText && tok.Name.Local == "status" {
+				if err := writeXMLStatusText(&buf, dec, tok, opts.prefix); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if depth == 1 && opts.detailCDATA && tok.Name.Local == "detail" {
+				if err := writeXMLCDATA(&buf, dec, tok, opts.prefix); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			depth++
+			buf.WriteByte('<')
+			writeXMLName(&buf, opts.prefix, tok.Name.Local)
+			for _, attr := range tok.Attr {
+				name := attr.Name.Local
+				if name == "xmlns" && attr.Name.Space == "" && opts.prefix != "" {
+					name = "xmlns:" + opts.prefix
+				}
+				buf.WriteByte(' ')
+				buf.WriteString(name)
+				buf.WriteString(`="`)
+				_ = xml.EscapeText(&buf, []byte(attr.Value)) // writing to bytes.Buffer never fails.
+				buf.WriteByte('"')
+			}
+			open = true
+		case xml.EndElement:
+			depth--
+			if open {
+				buf.WriteString("/>")
+				open = false
+				continue
+			}
+			buf.WriteString("</")
+			writeXMLName(&buf, opts.prefix, tok.Name.Local)
+			buf.WriteByte('>')
+		case xml.CharData:
+			_ = xml.EscapeText(&buf, tok)
+		case xml.Comment:
+			buf.WriteString("<!--")
+			buf.Write(tok)
+			buf.WriteString("-->")
+		}
+	}
+}
+
+// writeXMLStatusText writes the status element with the status code as the code attribute and the status text as the
+// content, the numeric content of the original element is consumed from dec.
+func writeXMLStatusText(buf *bytes.Buffer, dec *xml.Decoder, start xml.StartElement, prefix string) error {
+	var text string
+	if err := dec.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil {
+		return fmt.Errorf("xml: status: %w", err)
+	}
+
+	buf.WriteByte('<')
+	writeXMLName(buf, prefix, "status")
+	buf.WriteString(` code="`)
+	buf.WriteString(strconv.Itoa(code))
+	buf.WriteString(`">`)
+	_ = xml.EscapeText(buf, []byte(statusTitle(code))) // writing to bytes.Buffer never fails.
+	buf.WriteString("</")
+	writeXMLName(buf, prefix, "status")
+	buf.WriteByte('>')
+	return nil
+}
+
+// writeXMLCDATA writes the element with its text content as a CDATA section, the original element is consumed from dec.
+func writeXMLCDATA(buf *bytes.Buffer, dec *xml.Decoder, start xml.StartElement, prefix string) error {
+	var text string
+	if err := dec.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+
+	buf.WriteByte('<')
+	writeXMLName(buf, prefix, start.Name.Local)
+	buf.WriteString("><![CDATA[")
+	buf.WriteString(strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>"))
+	buf.WriteString("]]></")
+	writeXMLName(buf, prefix, start.Name.Local)
+	buf.WriteByte('>')
+	return nil
+}
+
+// writeXMLName writes the element name with its namespace prefix, if any.
+func writeXMLName(buf *bytes.Buffer, prefix, local string) {
+	if prefix != "" {
+		buf.WriteString(prefix)
+		buf.WriteByte(':')
+	}
+	buf.WriteString(local)
+}
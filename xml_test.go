@@ -0,0 +1,223 @@
+package problemdetail_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josestg/problemdetail"
+)
+
+func TestWriteXML_WithXMLNamespacePrefix(t *testing.T) {
+	data := BalanceProblemDetail{
+		ProblemDetail: problemdetail.New(
+			"https://example.com/probs/out-of-credit",
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithXMLNamespacePrefix("p"),
+		),
+		Balance:  30,
+		Accounts: []string{"/account/12345"},
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, &data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `<p:problem xmlns:p="urn:ietf:rfc:7807"><p:type>https://example.com/probs/out-of-credit</p:type><p:title>You do not have enough credit.</p:title><p:status>403</p:status><p:balance>30</p:balance><p:accounts>/account/12345</p:accounts></p:problem>`
+	rawGot := strings.TrimSpace(rec.Body.String())
+
+	expectTrue(t, rawGot == rawExp)
+	expectTrue(t, rec.Code == 403)
+}
+
+func TestWriteXML_WithEmitEmptyCollections(t *testing.T) {
+	data := BalanceProblemDetail{
+		ProblemDetail: problemdetail.New(
+			"https://example.com/probs/out-of-credit",
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithTitle("You do not have enough credit."),
+			problemdetail.WithExtension("cards", []string{}),
+			problemdetail.WithExtension("loans", []string(nil)),
+			problemdetail.WithEmitEmptyCollections(),
+		),
+		Balance:  30,
+		Accounts: []string{},
+	}
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, &data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/probs/out-of-credit</type><title>You do not have enough credit.</title><status>403</status><balance>30</balance><accounts/><cards/></problem>`
+	rawGot := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, rawGot == rawExp)
+
+	data.Accounts = nil
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteXML(rec, &data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp = `<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/probs/out-of-credit</type><title>You do not have enough credit.</title><status>403</status><balance>30</balance><cards/></problem>`
+	rawGot = strings.TrimSpace(rec.Body.String())
+	expectTrue(t, rawGot == rawExp)
+}
+
+func TestWriteXML_WithTimeExtension(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("timestamp", timestamp),
+		problemdetail.WithExtension("until", &timestamp),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 503)
+	expectTrue(t, err == nil)
+
+	rawExp := `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Service Unavailable</title><status>503</status><timestamp>2024-01-02T03:04:05Z</timestamp><until>2024-01-02T03:04:05Z</until></problem>`
+	rawGot := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, rawGot == rawExp)
+}
+
+func TestWriteXML_WithXMLStatusText(t *testing.T) {
+	data := problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithXMLStatusText(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/probs/out-of-credit</type><title>You do not have enough credit.</title><status code="403">Forbidden</status></problem>`
+	rawGot := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, rawGot == rawExp)
+
+	data = problemdetail.New("https://example.com/probs/out-of-credit",
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithTitle("You do not have enough credit."),
+		problemdetail.WithXMLStatusText(),
+		problemdetail.WithXMLNamespacePrefix("p"),
+	)
+
+	rec = httptest.NewRecorder()
+	err = problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp = `<p:problem xmlns:p="urn:ietf:rfc:7807"><p:type>https://example.com/probs/out-of-credit</p:type><p:title>You do not have enough credit.</p:title><p:status code="403">Forbidden</p:status></p:problem>`
+	rawGot = strings.TrimSpace(rec.Body.String())
+	expectTrue(t, rawGot == rawExp)
+}
+
+func TestWriteXML_WithXMLDetailCDATA(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithDetail("Your balance is < 50 & the note reads ]]> here."),
+		problemdetail.WithXMLDetailCDATA(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Forbidden</title><status>403</status><detail><![CDATA[Your balance is < 50 & the note reads ]]]]><![CDATA[> here.]]></detail></problem>`
+	rawGot := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, rawGot == rawExp)
+
+	pd, _, err := problemdetail.ReadXML(strings.NewReader(rawGot))
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Detail == "Your balance is < 50 & the note reads ]]> here.")
+}
+
+func TestWriteXML_WithNestedExtensions(t *testing.T) {
+	type card struct {
+		Last4   string `json:"last4"`
+		Expired bool   `json:"expired"`
+	}
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("balance", map[string]any{"current": 30, "cost": 50, "currency": nil}),
+		problemdetail.WithExtension("cards", []card{{Last4: "4242", Expired: true}, {Last4: "1881"}}),
+		problemdetail.WithExtension("primary", &card{Last4: "4242"}),
+		problemdetail.WithExtension("tags", []string{"billing", "credit"}),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Forbidden</title><status>403</status>` +
+		`<balance><cost>50</cost><currency></currency><current>30</current></balance>` +
+		`<cards><expired>true</expired><last4>4242</last4></cards><cards><expired>false</expired><last4>1881</last4></cards>` +
+		`<primary><expired>false</expired><last4>4242</last4></primary>` +
+		`<tags>billing</tags><tags>credit</tags></problem>`
+	rawGot := strings.TrimSpace(rec.Body.String())
+	expectTrue(t, rawGot == rawExp)
+}
+
+func TestWriteXML_WithInvalidExtensionName(t *testing.T) {
+	for _, name := range []string{"2fa", "user id", "a<b"} {
+		data := problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithExtension(name, true),
+		)
+
+		rec := httptest.NewRecorder()
+		rec.Code = 0
+		err := problemdetail.WriteXML(rec, data, 403)
+		expectTrue(t, errors.Is(err, problemdetail.ErrXMLName))
+		expectTrue(t, errors.Is(err, problemdetail.ErrMarshal))
+		expectTrue(t, strings.Contains(err.Error(), name))
+		expectTrue(t, rec.Code == 0)
+		expectTrue(t, rec.Body.Len() == 0)
+	}
+}
+
+func TestWriteXML_WithInvalidNestedName(t *testing.T) {
+	for _, name := range []string{"2fa", "user id", "-id", ""} {
+		data := problemdetail.New(problemdetail.Untyped,
+			problemdetail.WithValidateLevel(problemdetail.LStandard),
+			problemdetail.WithExtension("security", map[string]any{name: true}),
+		)
+
+		rec := httptest.NewRecorder()
+		rec.Code = 0
+		err := problemdetail.WriteXML(rec, data, 403)
+		expectTrue(t, errors.Is(err, problemdetail.ErrXMLName))
+		expectTrue(t, errors.Is(err, problemdetail.ErrMarshal))
+		expectTrue(t, rec.Code == 0)
+		expectTrue(t, rec.Body.Len() == 0)
+	}
+
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithExtension("security", map[string]any{"two-factor.v2": true, "_id": 1, "müller": "x"}),
+	)
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+	expectTrue(t, strings.Contains(rec.Body.String(), "<security><_id>1</_id><müller>x</müller><two-factor.v2>true</two-factor.v2></security>"))
+}
+
+func TestWriteXML_WithXMLDeclaration(t *testing.T) {
+	data := problemdetail.New(problemdetail.Untyped,
+		problemdetail.WithValidateLevel(problemdetail.LStandard),
+		problemdetail.WithXMLDeclaration(),
+	)
+
+	rec := httptest.NewRecorder()
+	err := problemdetail.WriteXML(rec, data, 403)
+	expectTrue(t, err == nil)
+
+	rawExp := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Forbidden</title><status>403</status></problem>`
+	expectTrue(t, rec.Body.String() == rawExp)
+
+	pd, _, err := problemdetail.ReadXML(strings.NewReader(rec.Body.String()))
+	expectTrue(t, err == nil)
+	expectTrue(t, pd.Status == 403)
+}